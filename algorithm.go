@@ -0,0 +1,86 @@
+package hmacsig
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrAlgorithmMismatch is the failure reason, with OptionStrictAlgorithm, when
+// the signature declared an algorithm other than the one expected, e.g.
+// "sha1=" sent to a SHA-256 handler. It wraps ErrSignaturePrefix.
+var ErrAlgorithmMismatch = fmt.Errorf("%w: algorithm mismatch", ErrSignaturePrefix)
+
+// OptionStrictAlgorithm reports signatures declaring an algorithm other than
+// the expected one as ErrAlgorithmMismatch, naming both algorithms, so that
+// misconfigured senders can be told apart from forged signatures in logs and
+// via ErrorFromContext. Like other malformed signatures they are passed to the
+// malformed signature handler.
+//
+// It applies only where the signature format is known, as configured by
+// Handler, Handler256, OptionSignaturePrefix or OptionSignatureFormat.
+func OptionStrictAlgorithm(mux *hmacSig) {
+	mux.strictAlgorithm = true
+}
+
+// ErrSHA1Only is the failure reason, with OptionRejectSHA1Only, when a request
+// carried GitHub's SHA-1 X-Hub-Signature but not X-Hub-Signature-256. It wraps
+// ErrMissingSignature.
+var ErrSHA1Only = fmt.Errorf("%w: sha1 signature without sha256, rejected by policy", ErrMissingSignature)
+
+// OptionRejectSHA1Only rejects requests carrying GitHub's SHA-1
+// X-Hub-Signature but not X-Hub-Signature-256 as ErrSHA1Only, before their
+// body is read, even though the SHA-1 signature may be valid. It is intended
+// for enforcing a migration deadline for senders to upgrade to SHA-256. Like
+// other missing signatures they are passed to the missing signature handler.
+//
+// It is a policy check only, to be combined with Handler256 or
+// OptionPreferSHA256, which configure the SHA-256 signature to be validated.
+func OptionRejectSHA1Only(mux *hmacSig) {
+	mux.rejectSHA1Only = true
+}
+
+// sha1Only reports whether r is to be rejected by OptionRejectSHA1Only
+func (xh *hmacSig) sha1Only(r *http.Request) bool {
+	return xh.rejectSHA1Only && r.Header.Get(GithubSignatureHeader256) == "" &&
+		r.Header.Get(GithubSignatureHeader) != ""
+}
+
+// malformed returns the reason sig, which is not well formed for c, is
+// rejected
+func (xh *hmacSig) malformed(c check, sig string) error {
+	if !xh.strictAlgorithm || c.format == nil {
+		return ErrMalformedSignature
+	}
+
+	got, want := declaredAlgorithm(sig), c.format.algorithm()
+	if got == "" || strings.EqualFold(got, want) {
+		return ErrMalformedSignature
+	}
+
+	return fmt.Errorf("%w: got %q, want %q", ErrAlgorithmMismatch, got, want)
+}
+
+// algorithm returns the algorithm named by the prefix of f, less its
+// separator, e.g. "sha256" for "sha256="
+func (f *signatureFormat) algorithm() string {
+	return strings.TrimRight(f.prefix, "=:")
+}
+
+// declaredAlgorithm returns the algorithm sig declares before an "=" or ":"
+// separator, or "" if it declares none
+func declaredAlgorithm(sig string) string {
+	i := strings.IndexAny(sig, "=:")
+	// a trailing separator is base64 padding rather than a declaration
+	if i <= 0 || i == len(sig)-1 {
+		return ""
+	}
+
+	for _, c := range sig[:i] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+			return ""
+		}
+	}
+
+	return sig[:i]
+}
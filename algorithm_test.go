@@ -0,0 +1,137 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStrictAlgorithm(t *testing.T) {
+	body := []byte("This body is super")
+	sha1Sig := SignSHA1(body, "EvenDifferentKey")
+	sha256Sig := "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"
+
+	tt := []struct {
+		name     string
+		sig      string
+		options  []Option
+		status   int
+		mismatch bool
+	}{
+		{"matching algorithm", sha256Sig, []Option{OptionStrictAlgorithm}, http.StatusOK, false},
+		{"other algorithm", sha1Sig, []Option{OptionStrictAlgorithm}, http.StatusBadRequest, true},
+		{"other algorithm, not strict", sha1Sig, nil, http.StatusBadRequest, false},
+		{"no algorithm", "814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", []Option{OptionStrictAlgorithm}, http.StatusBadRequest, false},
+		{"bad digest", "sha256=zz", []Option{OptionStrictAlgorithm}, http.StatusBadRequest, false},
+		{"custom prefix", "sha1:" + sha1Sig[5:], []Option{OptionSignaturePrefix("sha256:"), OptionStrictAlgorithm}, http.StatusBadRequest, true},
+		{"reuse GetBody", sha1Sig, []Option{OptionStrictAlgorithm, OptionReuseGetBody}, http.StatusBadRequest, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		var reason error
+		malformed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reason = ErrorFromContext(r.Context())
+			DefaultMalformedSignatureHandler(w, r)
+		})
+
+		options := append([]Option{OptionMalformedSignatureHandler(malformed)}, tc.options...)
+		Handler256(x, "EvenDifferentKey", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if errors.Is(reason, ErrAlgorithmMismatch) != tc.mismatch {
+			t.Errorf("%s: expected algorithm mismatch %v; got %v", tc.name, tc.mismatch, reason)
+		}
+	}
+}
+
+func TestDeclaredAlgorithm(t *testing.T) {
+	tt := []struct {
+		sig  string
+		want string
+	}{
+		{"sha256=abc", "sha256"},
+		{"SHA-512:abc", "SHA-512"},
+		{"abc", ""},
+		{"=abc", ""},
+		{"sha 1=abc", ""},
+		{"q83vEjRWeJA=", ""},
+	}
+
+	for _, tc := range tt {
+		if got := declaredAlgorithm(tc.sig); got != tc.want {
+			t.Errorf("expected %q for %q; got %q", tc.want, tc.sig, got)
+		}
+	}
+}
+
+func TestRejectSHA1Only(t *testing.T) {
+	body := []byte("This body is super")
+	sha1Sig := SignSHA1(body, "EvenDifferentKey")
+	sha256Sig := SignSHA256(body, "EvenDifferentKey")
+
+	tt := []struct {
+		name    string
+		sha1    string
+		sha256  string
+		options []Option
+		status  int
+		reason  error
+	}{
+		{"sha1 only", sha1Sig, "", []Option{OptionPreferSHA256, OptionRejectSHA1Only}, http.StatusForbidden, ErrSHA1Only},
+		{"sha1 only, handler256", sha1Sig, "", []Option{OptionRejectSHA1Only}, http.StatusForbidden, ErrSHA1Only},
+		{"sha1 only, no policy", sha1Sig, "", []Option{OptionPreferSHA256}, http.StatusOK, nil},
+		{"sha256 only", "", sha256Sig, []Option{OptionPreferSHA256, OptionRejectSHA1Only}, http.StatusOK, nil},
+		{"both", sha1Sig, sha256Sig, []Option{OptionPreferSHA256, OptionRejectSHA1Only}, http.StatusOK, nil},
+		{"both, bad sha256", sha1Sig, SignSHA256(body, "wrong"), []Option{OptionPreferSHA256, OptionRejectSHA1Only}, http.StatusForbidden, ErrSignatureMismatch},
+		{"neither", "", "", []Option{OptionPreferSHA256, OptionRejectSHA1Only}, http.StatusForbidden, ErrMissingSignature},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		if tc.sha1 != "" {
+			req.Header.Set(GithubSignatureHeader, tc.sha1)
+		}
+		if tc.sha256 != "" {
+			req.Header.Set(GithubSignatureHeader256, tc.sha256)
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		var reason error
+		failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reason = ErrorFromContext(r.Context())
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		options := append([]Option{OptionMissingSignatureHandler(failed), OptionVerifyFailedHandler(failed)}, tc.options...)
+		Handler256(x, "EvenDifferentKey", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if !errors.Is(reason, tc.reason) || (tc.reason == nil && reason != nil) {
+			t.Errorf("%s: expected reason '%v'; got '%v'", tc.name, tc.reason, reason)
+		}
+
+		if tc.reason == ErrMissingSignature && errors.Is(reason, ErrSHA1Only) {
+			t.Errorf("%s: expected missing signature distinct from sha1 only", tc.name)
+		}
+	}
+}
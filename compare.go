@@ -0,0 +1,24 @@
+package hmacsig
+
+import "crypto/subtle"
+
+// equalDigest reports whether sig, a decoded signature, equals the expected
+// digest. hmac.Equal returns early when the lengths differ; here sig is
+// instead copied into a buffer of the expected length and compared in full,
+// so the time taken depends only on the length of expected, which is public.
+func equalDigest(expected, sig []byte) bool {
+	var arr [64]byte
+	var buf []byte
+	if len(expected) <= len(arr) {
+		buf = arr[:len(expected)]
+	} else {
+		buf = make([]byte, len(expected))
+	}
+
+	copy(buf, sig)
+
+	eq := subtle.ConstantTimeCompare(expected, buf)
+	eqLen := subtle.ConstantTimeEq(int32(len(sig)), int32(len(expected)))
+
+	return eq&eqLen == 1
+}
@@ -0,0 +1,68 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestEqualDigest(t *testing.T) {
+	expected := sha256HMAC.digest([]byte("This is the body of the request"), []byte("supersecret"))
+	long := bytes.Repeat([]byte{0xab}, 100)
+
+	tt := []struct {
+		name     string
+		expected []byte
+		sig      []byte
+		equal    bool
+	}{
+		{"equal", expected, append([]byte(nil), expected...), true},
+		{"differs", expected, append(append([]byte(nil), expected[:31]...), expected[31]^1), false},
+		{"truncated", expected, expected[:16], false},
+		{"extended", expected, append(append([]byte(nil), expected...), 0), false},
+		{"zero padded", append(append([]byte(nil), expected[:16]...), make([]byte, 16)...), expected[:16], false},
+		{"empty", expected, []byte{}, false},
+		{"nil", expected, nil, false},
+		{"both empty", []byte{}, []byte{}, true},
+		{"long equal", long, append([]byte(nil), long...), true},
+		{"long truncated", long, long[:64], false},
+	}
+
+	for _, tc := range tt {
+		if eq := equalDigest(tc.expected, tc.sig); eq != tc.equal {
+			t.Errorf("%s: expected %v; got %v", tc.name, tc.equal, eq)
+		}
+	}
+}
+
+func TestValidateMismatchedLength(t *testing.T) {
+	body := []byte("This is the body of the request")
+	sig := SignSHA256(body, "supersecret")
+
+	tt := []struct {
+		sig string
+		err error
+	}{
+		{sig, nil},
+		{sig[:len(sig)-2], ErrSignatureLength},
+		{sig + "00", ErrSignatureLength},
+		{"sha256=", ErrSignatureLength},
+		{sig[:len(sig)-2] + "00", ErrSignatureMismatch},
+	}
+
+	for _, tc := range tt {
+		err := SHA256ValidatorE(body, tc.sig, "supersecret")
+		if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+			t.Errorf("expected '%v' for '%s'; got '%v'", tc.err, tc.sig, err)
+		}
+
+		if v := SHA256Validator(body, tc.sig, "supersecret"); v != (tc.err == nil) {
+			t.Errorf("expected valid %v for '%s'; got %v", tc.err == nil, tc.sig, v)
+		}
+
+		if ok, _ := ValidateReader(bytes.NewReader(body), tc.sig, "supersecret", sha256.New, "sha256="); ok != (tc.err == nil) {
+			t.Errorf("expected reader valid %v for '%s'; got %v", tc.err == nil, tc.sig, ok)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package hmacsig
+
+// AnyValidator returns a SignatureValidator which accepts a signature if any
+// of vs does, for instance to accept signatures from senders using a mix of
+// algorithms:
+//
+//	OptionSignatureValidator(AnyValidator(SHA256Validator, SHA1Validator))
+//
+// Every validator is run regardless of the outcome of the others, so the time
+// taken does not reveal which of them accepted. With no validators every
+// signature is rejected.
+func AnyValidator(vs ...SignatureValidator) SignatureValidator {
+	vs = append([]SignatureValidator(nil), vs...)
+
+	return func(body []byte, sig, secret string) bool {
+		ok := false
+		for _, v := range vs {
+			if v(body, sig, secret) {
+				ok = true
+			}
+		}
+
+		return ok
+	}
+}
+
+// AllValidator returns a SignatureValidator which accepts a signature only if
+// every one of vs does. As with AnyValidator, every validator is run
+// regardless of the outcome of the others. With no validators every signature
+// is rejected.
+func AllValidator(vs ...SignatureValidator) SignatureValidator {
+	vs = append([]SignatureValidator(nil), vs...)
+
+	return func(body []byte, sig, secret string) bool {
+		ok := len(vs) > 0
+		for _, v := range vs {
+			if !v(body, sig, secret) {
+				ok = false
+			}
+		}
+
+		return ok
+	}
+}
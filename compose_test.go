@@ -0,0 +1,112 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnyValidator(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		vs    []SignatureValidator
+		sig   string
+		valid bool
+	}{
+		{[]SignatureValidator{SHA256Validator, SHA1Validator}, SignSHA256(body, "supersecret"), true},
+		{[]SignatureValidator{SHA256Validator, SHA1Validator}, SignSHA1(body, "supersecret"), true},
+		{[]SignatureValidator{SHA256Validator, SHA1Validator}, SignSHA1(body, "wrongsecret"), false},
+		{[]SignatureValidator{SHA256Validator}, SignSHA1(body, "supersecret"), false},
+		{nil, SignSHA256(body, "supersecret"), false},
+	}
+
+	for _, tc := range tt {
+		if v := AnyValidator(tc.vs...)(body, tc.sig, "supersecret"); v != tc.valid {
+			t.Errorf("expected valid %v for '%s'; got %v", tc.valid, tc.sig, v)
+		}
+	}
+}
+
+func TestAnyValidatorRunsAll(t *testing.T) {
+	calls := 0
+	counting := func(ok bool) SignatureValidator {
+		return func(body []byte, sig, secret string) bool {
+			calls++
+			return ok
+		}
+	}
+
+	AnyValidator(counting(true), counting(false), counting(true))(nil, "", "")
+	if calls != 3 {
+		t.Errorf("expected 3 validators run; got %d", calls)
+	}
+
+	calls = 0
+	AllValidator(counting(false), counting(true), counting(false))(nil, "", "")
+	if calls != 3 {
+		t.Errorf("expected 3 validators run; got %d", calls)
+	}
+}
+
+func TestAllValidator(t *testing.T) {
+	body := []byte("This is the body of the request")
+	sig := SignSHA256(body, "supersecret")
+
+	hasPrefix := func(body []byte, sig, secret string) bool {
+		return strings.HasPrefix(sig, "sha256=")
+	}
+
+	tt := []struct {
+		vs    []SignatureValidator
+		sig   string
+		valid bool
+	}{
+		{[]SignatureValidator{SHA256Validator, hasPrefix}, sig, true},
+		{[]SignatureValidator{SHA256Validator, SHA1Validator}, sig, false},
+		{[]SignatureValidator{hasPrefix}, SignSHA256(body, "wrongsecret"), true},
+		{[]SignatureValidator{SHA256Validator, hasPrefix}, SignSHA256(body, "wrongsecret"), false},
+		{nil, sig, false},
+	}
+
+	for _, tc := range tt {
+		if v := AllValidator(tc.vs...)(body, tc.sig, "supersecret"); v != tc.valid {
+			t.Errorf("expected valid %v for '%s'; got %v", tc.valid, tc.sig, v)
+		}
+	}
+}
+
+func TestAnyValidatorHandler(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		sig    string
+		status int
+	}{
+		{SignSHA256(body, "supersecret"), http.StatusOK},
+		{SignSHA1(body, "supersecret"), http.StatusOK},
+		{Sign(body, "supersecret", sha512.New, "sha512="), http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("X-Signature", tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret",
+			OptionHeader("X-Signature"),
+			OptionSignatureValidator(AnyValidator(SHA256Validator, SHA1Validator)),
+		).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package hmacsig
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// MsgBusy is the message returned in the body when the request was rejected
+// because the number of bodies being read reached the limit configured by
+// OptionMaxConcurrentReads
+const MsgBusy = "Too many requests being verified, try again later"
+
+// ErrBusy is the failure reason when no slot configured by
+// OptionMaxConcurrentReads became free within the configured wait
+var ErrBusy = errors.New("hmacsig: too many concurrent body reads")
+
+// OptionMaxConcurrentReads limits the number of requests whose bodies are
+// read and buffered at once to n, as coarse backpressure against bursts of
+// deliveries exhausting memory. A request arriving at the limit waits up to
+// wait for another to finish before being passed to the busy handler.
+//
+// A slot is held from the start of reading the body until the wrapped handler
+// returns, as that is how long the buffered body is retained. Requests whose
+// signature is verified as the body is read, see OptionVerifyOnRead, are not
+// buffered and are not limited. The limit is shared by the routes configured
+// by OptionRoutes, unless a route configures its own. A value of zero or
+// less, the default, means no limit.
+func OptionMaxConcurrentReads(n int, wait time.Duration) Option {
+	return func(mux *hmacSig) {
+		mux.readSlots = nil
+		if n > 0 {
+			mux.readSlots = make(chan struct{}, n)
+		}
+		mux.readSlotWait = wait
+	}
+}
+
+// OptionBusyHandler configures the http.Handler called when a request is
+// rejected by the limit configured by OptionMaxConcurrentReads
+func OptionBusyHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.busyHandler = handler
+	}
+}
+
+// DefaultBusyHandler is the default response to a request rejected by the
+// limit configured by OptionMaxConcurrentReads. It responds 503 Service
+// Unavailable, asking the sender to retry after a second.
+func DefaultBusyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, MsgBusy, http.StatusServiceUnavailable)
+}
+
+// JSONBusyHandler responds to a request rejected by the limit configured by
+// OptionMaxConcurrentReads with a JSON body of the form {"error":"..."}
+func JSONBusyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	jsonError(w, MsgBusy, http.StatusServiceUnavailable)
+}
+
+// acquireRead takes a slot configured by OptionMaxConcurrentReads, returning
+// the function releasing it. It returns ErrBusy if none becomes free within
+// the configured wait, or errCanceled if the context of r is done first.
+func (xh *hmacSig) acquireRead(r *http.Request) (func(), error) {
+	if xh.readSlots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-xh.readSlots }
+
+	select {
+	case xh.readSlots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if xh.readSlotWait <= 0 {
+		return nil, ErrBusy
+	}
+
+	t := time.NewTimer(xh.readSlotWait)
+	defer t.Stop()
+
+	select {
+	case xh.readSlots <- struct{}{}:
+		return release, nil
+	case <-t.C:
+		return nil, ErrBusy
+	case <-r.Context().Done():
+		return nil, errCanceled
+	}
+}
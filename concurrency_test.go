@@ -0,0 +1,166 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentReads(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		wait    time.Duration
+		release bool
+		status  int
+		err     error
+	}{
+		{0, false, http.StatusServiceUnavailable, ErrBusy},
+		{10 * time.Millisecond, false, http.StatusServiceUnavailable, ErrBusy},
+		{time.Second, true, http.StatusOK, nil},
+	}
+
+	for _, tc := range tt {
+		entered := make(chan struct{}, 2)
+		unblock := make(chan struct{})
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			if r.Header.Get("X-Block") != "" {
+				<-unblock
+			}
+		})
+
+		var got error
+		busy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = ErrorFromContext(r.Context())
+			DefaultBusyHandler(w, r)
+		})
+
+		xhs := Handler(x, "supersecret", OptionMaxConcurrentReads(1, tc.wait), OptionBusyHandler(busy))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+			req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+			req.Header.Set("X-Block", "1")
+			xhs.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+		<-entered
+
+		if tc.release {
+			time.AfterFunc(10*time.Millisecond, func() { close(unblock) })
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		rec := httptest.NewRecorder()
+		xhs.ServeHTTP(rec, req)
+
+		if !tc.release {
+			close(unblock)
+		}
+		wg.Wait()
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d with wait %v; got %v", tc.status, tc.wait, res.Status)
+		}
+
+		if !errors.Is(got, tc.err) {
+			t.Errorf("expected reason '%v' with wait %v; got '%v'", tc.err, tc.wait, got)
+		}
+	}
+}
+
+func TestMaxConcurrentReadsLimit(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	var active, peak int32
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+	})
+
+	xhs := Handler(x, "supersecret", OptionMaxConcurrentReads(3, time.Second))
+
+	var wg sync.WaitGroup
+	var ok int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+			req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+			rec := httptest.NewRecorder()
+			xhs.ServeHTTP(rec, req)
+
+			if rec.Code == http.StatusOK {
+				atomic.AddInt32(&ok, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent requests; got %d", peak)
+	}
+
+	if ok != 20 {
+		t.Errorf("expected all 20 requests to succeed; got %d", ok)
+	}
+}
+
+func TestMaxConcurrentReadsRoutes(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	entered := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+	})
+
+	xhs := Handler(x, "",
+		OptionRoutes(Route{Path: "/a", Secret: "supersecret"}, Route{Path: "/b", Secret: "supersecret"}),
+		OptionMaxConcurrentReads(1, 0))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		req, _ := http.NewRequest("POST", "http://localhost/a", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		xhs.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-entered
+
+	req, _ := http.NewRequest("POST", "http://localhost/b", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	rec := httptest.NewRecorder()
+	xhs.ServeHTTP(rec, req)
+
+	close(unblock)
+	wg.Wait()
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the limit to span routes with status %d; got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
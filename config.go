@@ -0,0 +1,273 @@
+package hmacsig
+
+import (
+	"net/http"
+	"time"
+)
+
+// Config is a struct alternative to the functional options accepted by
+// Handler, covering the commonly configured subset of them. Each field mirrors
+// the Option of the same name; zero values leave the corresponding default in
+// place. Options without a field, such as OptionJSONErrors, may be passed to
+// New alongside the Config.
+type Config struct {
+	// Secret is the secret signatures are validated against
+	Secret string
+
+	// Secrets mirrors OptionSecrets and takes precedence over Secret
+	Secrets []string
+
+	// SecretFunc mirrors OptionSecretFunc
+	SecretFunc func(r *http.Request) (string, error)
+
+	// SecretErrorHandler mirrors OptionSecretErrorHandler
+	SecretErrorHandler http.Handler
+
+	// DefaultsSHA256 mirrors OptionDefaultsSHA256 and is applied before Header
+	// and Validator
+	DefaultsSHA256 bool
+
+	// Header mirrors OptionHeader
+	Header string
+
+	// ValidatorName mirrors OptionValidatorName
+	ValidatorName string
+
+	// Validator mirrors OptionSignatureValidator and takes precedence over
+	// ValidatorName
+	Validator SignatureValidator
+
+	// ValidatorE mirrors OptionSignatureValidatorE and takes precedence over
+	// Validator
+	ValidatorE SignatureValidatorE
+
+	// RequestValidator mirrors OptionRequestValidator and takes precedence
+	// over Validator and ValidatorE
+	RequestValidator RequestValidator
+
+	// MissingSignatureHandler mirrors OptionMissingSignatureHandler
+	MissingSignatureHandler http.Handler
+
+	// MissingSignatureStatus mirrors OptionMissingSignatureStatus
+	MissingSignatureStatus int
+
+	// MissingSignatureMessage mirrors OptionMissingSignatureMessage
+	MissingSignatureMessage string
+
+	// MalformedSignatureHandler mirrors OptionMalformedSignatureHandler
+	MalformedSignatureHandler http.Handler
+
+	// VerifyFailedHandler mirrors OptionVerifyFailedHandler
+	VerifyFailedHandler http.Handler
+
+	// VerifyFailedStatus mirrors OptionVerifyFailedStatus
+	VerifyFailedStatus int
+
+	// VerifyFailedMessage mirrors OptionVerifyFailedMessage
+	VerifyFailedMessage string
+
+	// MaxBodyBytes mirrors OptionMaxBodyBytes
+	MaxBodyBytes int64
+
+	// BodyTooLargeHandler mirrors OptionBodyTooLargeHandler
+	BodyTooLargeHandler http.Handler
+
+	// ReadErrorHandler mirrors OptionReadErrorHandler
+	ReadErrorHandler http.Handler
+
+	// RequireContentLength mirrors OptionRequireContentLength
+	RequireContentLength bool
+
+	// LengthRequiredHandler mirrors OptionLengthRequiredHandler
+	LengthRequiredHandler http.Handler
+
+	// ContentTypes mirrors OptionContentTypes
+	ContentTypes []string
+
+	// UnsupportedMediaTypeHandler mirrors OptionUnsupportedMediaTypeHandler
+	UnsupportedMediaTypeHandler http.Handler
+
+	// SpillThreshold and SpillDir mirror OptionSpillToDisk
+	SpillThreshold int64
+	SpillDir       string
+
+	// SkipPaths mirrors OptionSkipPaths
+	SkipPaths []string
+
+	// SkipMethods mirrors OptionSkipMethods
+	SkipMethods []string
+
+	// Skip mirrors OptionSkip
+	Skip func(r *http.Request) bool
+
+	// TimestampHeader and TimestampMaxAge mirror OptionTimestamp
+	TimestampHeader string
+	TimestampMaxAge time.Duration
+
+	// NonceHeader and NonceStore mirror OptionNonceStore
+	NonceHeader string
+	NonceStore  NonceStore
+
+	// ReplayHandler mirrors OptionReplayHandler
+	ReplayHandler http.Handler
+
+	// Routes mirrors OptionRoutes
+	Routes []Route
+
+	// AllowedCIDRs mirrors OptionAllowedCIDRs
+	AllowedCIDRs []string
+
+	// ReportOnly mirrors OptionReportOnly
+	ReportOnly bool
+
+	// ReadTimeout mirrors OptionReadTimeout
+	ReadTimeout time.Duration
+
+	// KeyDerivation mirrors OptionKeyDerivation
+	KeyDerivation func(r *http.Request, secret []byte) ([]byte, error)
+
+	// RejectSHA1Only mirrors OptionRejectSHA1Only
+	RejectSHA1Only bool
+
+	// Logger mirrors OptionLogger
+	Logger Logger
+
+	// Observer mirrors OptionObserver
+	Observer func(result Result, r *http.Request)
+
+	// TimedObserver mirrors OptionTimedObserver
+	TimedObserver func(result Result, r *http.Request, d time.Duration)
+}
+
+// New provides HMAC signature validating middleware configured by cfg.
+//
+// Any options passed are applied after those derived from cfg and so take
+// precedence over its fields.
+func New(h http.Handler, cfg Config, options ...Option) http.Handler {
+	return Handler(h, cfg.Secret, append(cfg.options(), options...)...)
+}
+
+// options translates the non-zero fields of the Config to Options
+func (cfg Config) options() []Option {
+	var opts []Option
+
+	if cfg.DefaultsSHA256 {
+		opts = append(opts, OptionDefaultsSHA256)
+	}
+	if len(cfg.Secrets) > 0 {
+		opts = append(opts, OptionSecrets(cfg.Secrets...))
+	}
+	if cfg.SecretFunc != nil {
+		opts = append(opts, OptionSecretFunc(cfg.SecretFunc))
+	}
+	if cfg.SecretErrorHandler != nil {
+		opts = append(opts, OptionSecretErrorHandler(cfg.SecretErrorHandler))
+	}
+	if cfg.Header != "" {
+		opts = append(opts, OptionHeader(cfg.Header))
+	}
+	if cfg.ValidatorName != "" {
+		opts = append(opts, OptionValidatorName(cfg.ValidatorName))
+	}
+	if cfg.Validator != nil {
+		opts = append(opts, OptionSignatureValidator(cfg.Validator))
+	}
+	if cfg.ValidatorE != nil {
+		opts = append(opts, OptionSignatureValidatorE(cfg.ValidatorE))
+	}
+	if cfg.RequestValidator != nil {
+		opts = append(opts, OptionRequestValidator(cfg.RequestValidator))
+	}
+	if cfg.MissingSignatureHandler != nil {
+		opts = append(opts, OptionMissingSignatureHandler(cfg.MissingSignatureHandler))
+	}
+	if cfg.MissingSignatureStatus != 0 {
+		opts = append(opts, OptionMissingSignatureStatus(cfg.MissingSignatureStatus))
+	}
+	if cfg.MissingSignatureMessage != "" {
+		opts = append(opts, OptionMissingSignatureMessage(cfg.MissingSignatureMessage))
+	}
+	if cfg.MalformedSignatureHandler != nil {
+		opts = append(opts, OptionMalformedSignatureHandler(cfg.MalformedSignatureHandler))
+	}
+	if cfg.VerifyFailedHandler != nil {
+		opts = append(opts, OptionVerifyFailedHandler(cfg.VerifyFailedHandler))
+	}
+	if cfg.VerifyFailedStatus != 0 {
+		opts = append(opts, OptionVerifyFailedStatus(cfg.VerifyFailedStatus))
+	}
+	if cfg.VerifyFailedMessage != "" {
+		opts = append(opts, OptionVerifyFailedMessage(cfg.VerifyFailedMessage))
+	}
+	if cfg.MaxBodyBytes > 0 {
+		opts = append(opts, OptionMaxBodyBytes(cfg.MaxBodyBytes))
+	}
+	if cfg.BodyTooLargeHandler != nil {
+		opts = append(opts, OptionBodyTooLargeHandler(cfg.BodyTooLargeHandler))
+	}
+	if cfg.ReadErrorHandler != nil {
+		opts = append(opts, OptionReadErrorHandler(cfg.ReadErrorHandler))
+	}
+	if cfg.RequireContentLength {
+		opts = append(opts, OptionRequireContentLength)
+	}
+	if cfg.LengthRequiredHandler != nil {
+		opts = append(opts, OptionLengthRequiredHandler(cfg.LengthRequiredHandler))
+	}
+	if len(cfg.ContentTypes) > 0 {
+		opts = append(opts, OptionContentTypes(cfg.ContentTypes...))
+	}
+	if cfg.UnsupportedMediaTypeHandler != nil {
+		opts = append(opts, OptionUnsupportedMediaTypeHandler(cfg.UnsupportedMediaTypeHandler))
+	}
+	if cfg.SpillThreshold > 0 {
+		opts = append(opts, OptionSpillToDisk(cfg.SpillThreshold, cfg.SpillDir))
+	}
+	if len(cfg.SkipPaths) > 0 {
+		opts = append(opts, OptionSkipPaths(cfg.SkipPaths...))
+	}
+	if len(cfg.SkipMethods) > 0 {
+		opts = append(opts, OptionSkipMethods(cfg.SkipMethods...))
+	}
+	if cfg.Skip != nil {
+		opts = append(opts, OptionSkip(cfg.Skip))
+	}
+	if cfg.TimestampHeader != "" {
+		opts = append(opts, OptionTimestamp(cfg.TimestampHeader, cfg.TimestampMaxAge))
+	}
+	if cfg.NonceStore != nil {
+		opts = append(opts, OptionNonceStore(cfg.NonceHeader, cfg.NonceStore))
+	}
+	if cfg.ReplayHandler != nil {
+		opts = append(opts, OptionReplayHandler(cfg.ReplayHandler))
+	}
+	if len(cfg.Routes) > 0 {
+		opts = append(opts, OptionRoutes(cfg.Routes...))
+	}
+	if len(cfg.AllowedCIDRs) > 0 {
+		opts = append(opts, OptionAllowedCIDRs(cfg.AllowedCIDRs...))
+	}
+	if cfg.ReportOnly {
+		opts = append(opts, OptionReportOnly)
+	}
+	if cfg.ReadTimeout > 0 {
+		opts = append(opts, OptionReadTimeout(cfg.ReadTimeout))
+	}
+	if cfg.KeyDerivation != nil {
+		opts = append(opts, OptionKeyDerivation(cfg.KeyDerivation))
+	}
+	if cfg.RejectSHA1Only {
+		opts = append(opts, OptionRejectSHA1Only)
+	}
+	if cfg.Logger != nil {
+		opts = append(opts, OptionLogger(cfg.Logger))
+	}
+	if cfg.Observer != nil {
+		opts = append(opts, OptionObserver(cfg.Observer))
+	}
+	if cfg.TimedObserver != nil {
+		opts = append(opts, OptionTimedObserver(cfg.TimedObserver))
+	}
+
+	return opts
+}
@@ -0,0 +1,66 @@
+package hmacsig
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	body := []byte("This body is super")
+
+	derived := func(r *http.Request, secret []byte) ([]byte, error) {
+		return []byte("derived"), nil
+	}
+
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		name    string
+		header  string
+		sig     string
+		cfg     Config
+		options []Option
+		status  int
+	}{
+		{"defaults", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret"}, nil, http.StatusOK},
+		{"sha256", GithubSignatureHeader256, SignSHA256(body, "supersecret"), Config{Secret: "supersecret", DefaultsSHA256: true}, nil, http.StatusOK},
+		{"header and validator", "X-Sig", SignSHA256(body, "supersecret"), Config{Secret: "supersecret", Header: "X-Sig", Validator: SHA256Validator}, nil, http.StatusOK},
+		{"secrets", GithubSignatureHeader, SignSHA1(body, "new"), Config{Secret: "supersecret", Secrets: []string{"old", "new"}}, nil, http.StatusOK},
+		{"max body", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", MaxBodyBytes: 4}, nil, http.StatusRequestEntityTooLarge},
+		{"skip", GithubSignatureHeader, "", Config{Secret: "supersecret", SkipMethods: []string{"POST"}}, nil, http.StatusOK},
+		{"skip paths", GithubSignatureHeader, "", Config{Secret: "supersecret", SkipPaths: []string{"localhost"}}, nil, http.StatusOK},
+		{"validator name", GithubSignatureHeader, SignSHA256(body, "supersecret"), Config{Secret: "supersecret", ValidatorName: "sha256"}, nil, http.StatusOK},
+		{"unknown validator name", GithubSignatureHeader, SignSHA256(body, "supersecret"), Config{Secret: "supersecret", ValidatorName: "nope"}, nil, http.StatusForbidden},
+		{"routes", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", Routes: []Route{{Path: "/github"}}}, nil, http.StatusNotFound},
+		{"allowed CIDRs", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", AllowedCIDRs: []string{"10.0.0.0/8"}}, nil, http.StatusForbidden},
+		{"report only", GithubSignatureHeader, SignSHA1(body, "wrong"), Config{Secret: "supersecret", ReportOnly: true}, nil, http.StatusOK},
+		{"read timeout", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", ReadTimeout: time.Minute}, nil, http.StatusOK},
+		{"key derivation", GithubSignatureHeader, SignSHA1(body, "derived"), Config{Secret: "supersecret", KeyDerivation: derived}, nil, http.StatusOK},
+		{"reject sha1 only", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", RejectSHA1Only: true}, nil, http.StatusForbidden},
+		{"failed handler", GithubSignatureHeader, SignSHA1(body, "wrong"), Config{Secret: "supersecret", VerifyFailedHandler: teapot}, nil, http.StatusTeapot},
+		{"option precedence", GithubSignatureHeader, SignSHA1(body, "supersecret"), Config{Secret: "supersecret", MaxBodyBytes: 4}, []Option{OptionMaxBodyBytes(0)}, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(tc.header, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := New(x, tc.cfg, tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+	}
+}
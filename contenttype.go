@@ -0,0 +1,65 @@
+package hmacsig
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MsgUnsupportedMediaType is the message returned in the body when the request
+// Content-Type is not one allowed by OptionContentTypes
+const MsgUnsupportedMediaType = "Unsupported Content-Type"
+
+// ErrUnsupportedMediaType is the failure reason when the request Content-Type
+// is not one allowed by OptionContentTypes
+var ErrUnsupportedMediaType = errors.New("hmacsig: unsupported content type")
+
+// OptionContentTypes configures the Content-Types accepted, matched
+// case-insensitively as prefixes of the request Content-Type header so that
+// "application/json" accepts "application/json; charset=utf-8". Requests with
+// any other Content-Type are passed to the unsupported media type handler
+// before the body is read.
+//
+// By default any Content-Type is accepted.
+func OptionContentTypes(types ...string) Option {
+	return func(mux *hmacSig) {
+		mux.contentTypes = types
+	}
+}
+
+// OptionUnsupportedMediaTypeHandler configures the http.Handler called when
+// the request Content-Type is not one allowed by OptionContentTypes
+func OptionUnsupportedMediaTypeHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.unsupportedMediaTypeHandler = handler
+	}
+}
+
+// DefaultUnsupportedMediaTypeHandler is the default response to a request
+// Content-Type not allowed by OptionContentTypes
+func DefaultUnsupportedMediaTypeHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgUnsupportedMediaType, http.StatusUnsupportedMediaType)
+}
+
+// JSONUnsupportedMediaTypeHandler responds to a request Content-Type not
+// allowed by OptionContentTypes with a JSON body of the form {"error":"..."}
+func JSONUnsupportedMediaTypeHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgUnsupportedMediaType, http.StatusUnsupportedMediaType)
+}
+
+// contentTypeAllowed reports whether the Content-Type of r is allowed by the
+// configured content types
+func (xh *hmacSig) contentTypeAllowed(r *http.Request) bool {
+	if len(xh.contentTypes) == 0 {
+		return true
+	}
+
+	ct := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Type")))
+	for _, t := range xh.contentTypes {
+		if strings.HasPrefix(ct, strings.ToLower(t)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package hmacsig
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentTypes(t *testing.T) {
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		contentType string
+		options     []Option
+		status      int
+	}{
+		{"text/plain", nil, http.StatusOK},
+		{"", nil, http.StatusOK},
+		{"application/json", []Option{OptionContentTypes("application/json")}, http.StatusOK},
+		{"Application/JSON; charset=utf-8", []Option{OptionContentTypes("application/json")}, http.StatusOK},
+		{"application/x-www-form-urlencoded", []Option{OptionContentTypes("application/json", "application/x-www-form-urlencoded")}, http.StatusOK},
+		{"text/plain", []Option{OptionContentTypes("application/json")}, http.StatusUnsupportedMediaType},
+		{"", []Option{OptionContentTypes("application/json")}, http.StatusUnsupportedMediaType},
+		{"text/plain", []Option{OptionContentTypes("application/json"), OptionUnsupportedMediaTypeHandler(teapot)}, http.StatusTeapot},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.contentType, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package hmacsig
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	errorContextKey contextKey = iota
+	verificationContextKey
+	headersContextKey
+	bodyContextKey
+	startContextKey
+	bodySizeContextKey
+)
+
+// Disposition describes whether a request passed to the wrapped handler was
+// verified
+type Disposition int
+
+const (
+	// DispositionNone indicates the request was not passed through Handler
+	DispositionNone Disposition = iota
+
+	// DispositionVerified indicates the signature was verified
+	DispositionVerified
+
+	// DispositionSkipped indicates verification was skipped, see
+	// OptionSkipMethods, OptionSkipPaths and OptionSkip
+	DispositionSkipped
+
+	// DispositionWaived indicates verification failed but the request was
+	// forwarded regardless, see OptionReportOnly
+	DispositionWaived
+
+	// DispositionPending indicates the body is verified as it is read, see
+	// OptionVerifyOnRead, and is not yet known to be authentic
+	DispositionPending
+)
+
+func (d Disposition) String() string {
+	switch d {
+	case DispositionNone:
+		return "none"
+	case DispositionVerified:
+		return "verified"
+	case DispositionSkipped:
+		return "skipped"
+	case DispositionWaived:
+		return "waived"
+	case DispositionPending:
+		return "pending"
+	}
+
+	return "unknown"
+}
+
+// Verification describes how a request was verified
+type Verification struct {
+	// Header is the HTTP Header the signature was read from
+	Header string
+
+	// Prefix is the signature prefix of the configured format, e.g. "sha256=".
+	// It is empty when the format is unknown, see OptionSignatureFormat.
+	Prefix string
+
+	// SecretIndex is the index of the secret the signature validated against,
+	// see OptionSecrets. During rotation, requests still validating against
+	// the old secret show it cannot yet be retired.
+	SecretIndex int
+
+	// SecretName is the name of the secret the signature validated against,
+	// as configured by OptionNamedSecrets. It is empty otherwise.
+	SecretName string
+
+	// BodySize is the size in bytes of the body read for verification.
+	// Compared against the limit configured by OptionMaxBodyBytes it shows how
+	// close requests come to being rejected.
+	BodySize int64
+
+	// TapErr is the error copying the body to the tap configured by
+	// OptionBodyTap, wrapping ErrBodyTap, when the request was delivered
+	// regardless. It is nil otherwise.
+	TapErr error
+
+	// Disposition is whether the request was verified. Only when it is
+	// DispositionVerified are the other fields set.
+	Disposition Disposition
+}
+
+// FromContext returns the Verification stored in the context of a request
+// passed to the wrapped handler. The boolean is true only when the request
+// passed HMAC verification; requests forwarded without it, for instance as
+// skipped or in report-only mode, return false with their Disposition set.
+func FromContext(ctx context.Context) (Verification, bool) {
+	v, _ := ctx.Value(verificationContextKey).(Verification)
+	return v, v.Disposition == DispositionVerified
+}
+
+// withDisposition returns r with a Verification of the given disposition
+// stored in its context
+func withDisposition(r *http.Request, d Disposition) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), verificationContextKey, Verification{Disposition: d}))
+}
+
+// ErrorFromContext returns the reason a request failed, as stored in the
+// context of the request passed to the failure handlers, e.g.
+// ErrMissingSignature or ErrSignatureMismatch. It returns nil when no failure
+// is recorded.
+func ErrorFromContext(ctx context.Context) error {
+	err, _ := ctx.Value(errorContextKey).(error)
+	return err
+}
+
+// OptionContextHeaders configures headers, e.g. GithubEventHeader and
+// GithubDeliveryHeader, whose values are copied into the request context for
+// retrieval via HeaderFromContext by the wrapped and failure handlers alike.
+// By default no headers are copied.
+func OptionContextHeaders(headers ...string) Option {
+	return func(mux *hmacSig) {
+		mux.contextHeaders = headers
+	}
+}
+
+// HeaderFromContext returns the value of a header copied into the context as
+// configured by OptionContextHeaders. The boolean is false when the header was
+// not configured to be copied.
+func HeaderFromContext(ctx context.Context, header string) (string, bool) {
+	headers, _ := ctx.Value(headersContextKey).(map[string]string)
+	v, ok := headers[http.CanonicalHeaderKey(header)]
+	return v, ok
+}
+
+// withContextHeaders returns r with the configured headers copied into its
+// context
+func (xh *hmacSig) withContextHeaders(r *http.Request) *http.Request {
+	if len(xh.contextHeaders) == 0 {
+		return r
+	}
+
+	headers := make(map[string]string, len(xh.contextHeaders))
+	for _, h := range xh.contextHeaders {
+		headers[http.CanonicalHeaderKey(h)] = r.Header.Get(h)
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), headersContextKey, headers))
+}
+
+// BodySizeFromContext returns the size in bytes of the body read for
+// verification, for instance for an observer configured by OptionObserver to
+// record alongside the Result. Unlike Verification it is also available for
+// rejected requests once their body has been read in full. The boolean is
+// false when the body was not read, including when it was rejected by
+// OptionMaxBodyBytes or could not be read.
+func BodySizeFromContext(ctx context.Context) (int64, bool) {
+	n, ok := ctx.Value(bodySizeContextKey).(int64)
+	return n, ok
+}
+
+// withBodySize returns r with the size of its body, read in full, recorded in
+// its context
+func withBodySize(r *http.Request, n int64) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), bodySizeContextKey, n))
+}
+
+// OptionBodyContext stores the body read for verification in the context of
+// verified requests, for retrieval via BodyFromContext without reading r.Body
+// again. It is opt-in as it keeps the body referenced for the lifetime of the
+// request context.
+func OptionBodyContext(mux *hmacSig) {
+	mux.bodyContext = true
+}
+
+// BodyFromContext returns the body stored in the context as configured by
+// OptionBodyContext. The returned slice shares its backing array with the
+// reader the wrapped handler's r.Body reads from and must not be modified.
+//
+// The boolean is false when the body was not stored, including for bodies
+// spilled to disk by OptionSpillToDisk.
+func BodyFromContext(ctx context.Context) ([]byte, bool) {
+	b, ok := ctx.Value(bodyContextKey).([]byte)
+	return b, ok
+}
@@ -0,0 +1,317 @@
+package hmacsig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorFromContext(t *testing.T) {
+	tt := []struct {
+		reqHeader  string
+		secretFunc func(r *http.Request) (string, error)
+		err        error
+	}{
+		{"", nil, ErrMissingSignature},
+		{"garbage", nil, ErrMalformedSignature},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", nil, ErrSignatureMismatch},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", func(r *http.Request) (string, error) {
+			return "", errors.New("unknown tenant")
+		}, ErrSecretLookup},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be executed")
+		})
+
+		var got error
+		failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = ErrorFromContext(r.Context())
+		})
+
+		options := []Option{OptionMissingSignatureHandler(failed), OptionMalformedSignatureHandler(failed), OptionVerifyFailedHandler(failed), OptionSecretErrorHandler(failed)}
+		if tc.secretFunc != nil {
+			options = append(options, OptionSecretFunc(tc.secretFunc))
+		}
+
+		xhs := Handler(x, "wrongsecret", options...)
+		xhs.ServeHTTP(rec, req)
+
+		if !errors.Is(got, tc.err) {
+			t.Errorf("expected reason '%v'; got '%v'", tc.err, got)
+		}
+	}
+
+	if err := ErrorFromContext(context.Background()); err != nil {
+		t.Errorf("expected nil reason; got '%v'", err)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	tt := []struct {
+		header    string
+		reqHeader string
+		body      string
+		options   []Option
+		expected  Verification
+	}{
+		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request",
+			[]Option{}, Verification{GithubSignatureHeader, "sha1=", 0, "", 31, nil, DispositionVerified}},
+		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request",
+			[]Option{OptionSecrets("oldsecret", "supersecret")}, Verification{GithubSignatureHeader, "sha1=", 1, "", 31, nil, DispositionVerified}},
+		{GithubSignatureHeader256, "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "This body is super",
+			[]Option{OptionDefaultsSHA256, OptionSecrets("EvenDifferentKey")}, Verification{GithubSignatureHeader256, "sha256=", 0, "", 18, nil, DispositionVerified}},
+		{"X-Sig", "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "This body is super",
+			[]Option{OptionHeader("X-Sig"), OptionSignatureValidator(SHA256Validator), OptionSecrets("EvenDifferentKey")}, Verification{"X-Sig", "", 0, "", 18, nil, DispositionVerified}},
+		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request",
+			[]Option{OptionNamedSecrets(NamedSecret{"new", "newsecret"}, NamedSecret{"old", "supersecret"})}, Verification{GithubSignatureHeader, "sha1=", 1, "old", 31, nil, DispositionVerified}},
+		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request",
+			[]Option{OptionNamedSecrets(NamedSecret{"old", "supersecret"}), OptionSecrets("supersecret")}, Verification{GithubSignatureHeader, "sha1=", 0, "", 31, nil, DispositionVerified}},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set(tc.header, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		executed := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			executed = true
+
+			v, ok := FromContext(r.Context())
+			if !ok {
+				t.Fatal("expected verification in context")
+			}
+
+			if v != tc.expected {
+				t.Errorf("expected verification %+v; got %+v", tc.expected, v)
+			}
+		})
+
+		xhs := Handler(x, "supersecret", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		if !executed {
+			t.Errorf("expected handler to be executed; got %v", rec.Result().Status)
+		}
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no verification in context")
+	}
+}
+
+func TestContextHeaders(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		sig string
+	}{
+		{SignSHA1(body, "supersecret")},
+		{SignSHA1(body, "wrongsecret")},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		req.Header.Set(GithubEventHeader, "push")
+		req.Header.Set(GithubDeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		rec := httptest.NewRecorder()
+
+		called := false
+		check := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+
+			if v, ok := HeaderFromContext(r.Context(), GithubEventHeader); !ok || v != "push" {
+				t.Errorf("expected event 'push'; got '%v' %v", v, ok)
+			}
+
+			if v, ok := HeaderFromContext(r.Context(), "x-github-delivery"); !ok || v != "72d3162e-cc78-11e3-81ab-4c9367dc0958" {
+				t.Errorf("expected delivery id; got '%v' %v", v, ok)
+			}
+
+			if v, ok := HeaderFromContext(r.Context(), "X-Other"); ok {
+				t.Errorf("expected unconfigured header to be absent; got '%v'", v)
+			}
+		})
+
+		xhs := Handler(check, "supersecret",
+			OptionContextHeaders(GithubEventHeader, GithubDeliveryHeader, "X-Missing"),
+			OptionVerifyFailedHandler(check))
+		xhs.ServeHTTP(rec, req)
+
+		if !called {
+			t.Error("expected handler to be called")
+		}
+	}
+}
+
+func TestContextHeadersDefault(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	req.Header.Set(GithubEventHeader, "push")
+	rec := httptest.NewRecorder()
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := HeaderFromContext(r.Context(), GithubEventHeader); ok {
+			t.Errorf("expected no headers in context; got '%v'", v)
+		}
+	})
+
+	Handler(x, "supersecret").ServeHTTP(rec, req)
+}
+
+func TestBodyFromContext(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		options []Option
+		stored  bool
+	}{
+		{nil, false},
+		{[]Option{OptionBodyContext}, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, ok := BodyFromContext(r.Context())
+			if ok != tc.stored {
+				t.Errorf("expected stored %v; got %v", tc.stored, ok)
+			}
+
+			if tc.stored && !bytes.Equal(b, body) {
+				t.Errorf("expected body '%s'; got '%s'", body, b)
+			}
+
+			rb, _ := io.ReadAll(r.Body)
+			if !bytes.Equal(rb, body) {
+				t.Errorf("expected r.Body '%s'; got '%s'", body, rb)
+			}
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+	}
+}
+
+func TestBodySizeFromContext(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		sig     string
+		options []Option
+		result  Result
+		size    int64
+		ok      bool
+	}{
+		{SignSHA1(body, "supersecret"), nil, ResultOK, 31, true},
+		{SignSHA1(body, "wrongsecret"), nil, ResultFailed, 31, true},
+		{SignSHA1(body, "supersecret"), []Option{OptionSpillToDisk(4, "")}, ResultOK, 31, true},
+		{SignSHA1(body, "supersecret"), []Option{OptionReuseGetBody}, ResultOK, 31, true},
+		{SignSHA1(body, "supersecret"), []Option{OptionMaxBodyBytes(8)}, ResultError, 0, false},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.ContentLength = -1
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v, _ := FromContext(r.Context()); v.BodySize != tc.size {
+				t.Errorf("expected verification body size %d; got %d", tc.size, v.BodySize)
+			}
+		})
+
+		observed := false
+		observer := func(result Result, r *http.Request) {
+			observed = true
+
+			if result != tc.result {
+				t.Errorf("expected result %v; got %v", tc.result, result)
+			}
+
+			n, ok := BodySizeFromContext(r.Context())
+			if n != tc.size || ok != tc.ok {
+				t.Errorf("expected body size %d %v; got %d %v", tc.size, tc.ok, n, ok)
+			}
+		}
+
+		options := append([]Option{OptionObserver(observer)}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if !observed {
+			t.Error("expected observer to be called")
+		}
+	}
+
+	if _, ok := BodySizeFromContext(context.Background()); ok {
+		t.Error("expected no body size in context")
+	}
+}
+
+func TestDisposition(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		method      string
+		sig         string
+		options     []Option
+		disposition Disposition
+		verified    bool
+	}{
+		{"POST", SignSHA1(body, "supersecret"), nil, DispositionVerified, true},
+		{"GET", "", []Option{OptionSkipMethods("GET")}, DispositionSkipped, false},
+		{"POST", "", []Option{OptionSkip(func(r *http.Request) bool { return true })}, DispositionSkipped, false},
+		{"POST", SignSHA1(body, "wrongsecret"), []Option{OptionReportOnly}, DispositionWaived, false},
+		{"POST", "", []Option{OptionReportOnly}, DispositionWaived, false},
+		{"POST", SignSHA1(body, "supersecret"), []Option{OptionReportOnly}, DispositionVerified, true},
+		{"POST", SignSHA1(body, "supersecret"), []Option{OptionVerifyOnRead}, DispositionPending, false},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		called := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+
+			v, ok := FromContext(r.Context())
+			if v.Disposition != tc.disposition || ok != tc.verified {
+				t.Errorf("expected disposition %v %v; got %v %v", tc.disposition, tc.verified, v.Disposition, ok)
+			}
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if !called {
+			t.Errorf("expected handler to be called for disposition %v", tc.disposition)
+		}
+	}
+
+	failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := FromContext(r.Context()); v.Disposition != DispositionNone || ok {
+			t.Errorf("expected no disposition for a rejected request; got %v %v", v.Disposition, ok)
+		}
+	})
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "wrongsecret"))
+	Handler(failed, "supersecret", OptionVerifyFailedHandler(failed)).ServeHTTP(httptest.NewRecorder(), req)
+}
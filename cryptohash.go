@@ -0,0 +1,63 @@
+package hmacsig
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrHashUnavailable is returned by ValidatorFor when the hash is unknown or
+// its implementation is not linked into the binary
+var ErrHashUnavailable = errors.New("hmacsig: hash unavailable")
+
+// ValidatorFor returns a SignatureValidator for signatures prefixed as
+// returned by HashPrefix, e.g. "sha256=" for crypto.SHA256, followed by the
+// hex encoded HMAC computed with h.
+//
+// The package implementing h must be imported, e.g. crypto/sha256, else an
+// error wrapping ErrHashUnavailable is returned. SHA-1, SHA-224, SHA-256,
+// SHA-384 and SHA-512 are always available.
+func ValidatorFor(h crypto.Hash) (SignatureValidator, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("%w: %v", ErrHashUnavailable, h)
+	}
+
+	return NewValidator(h.New, HashPrefix(h)), nil
+}
+
+// hashPrefixes are the signature prefixes of the hashes known to HashPrefix,
+// matching those of hmacsigsha3 and hmacsigblake2b where they overlap
+var hashPrefixes = map[crypto.Hash]string{
+	crypto.MD4:         "md4=",
+	crypto.MD5:         "md5=",
+	crypto.SHA1:        "sha1=",
+	crypto.SHA224:      "sha224=",
+	crypto.SHA256:      "sha256=",
+	crypto.SHA384:      "sha384=",
+	crypto.SHA512:      "sha512=",
+	crypto.MD5SHA1:     "md5sha1=",
+	crypto.RIPEMD160:   "ripemd160=",
+	crypto.SHA3_224:    "sha3-224=",
+	crypto.SHA3_256:    "sha3-256=",
+	crypto.SHA3_384:    "sha3-384=",
+	crypto.SHA3_512:    "sha3-512=",
+	crypto.SHA512_224:  "sha512-224=",
+	crypto.SHA512_256:  "sha512-256=",
+	crypto.BLAKE2s_256: "blake2s=",
+	crypto.BLAKE2b_256: "blake2b=",
+	crypto.BLAKE2b_384: "blake2b-384=",
+	crypto.BLAKE2b_512: "blake2b-512=",
+}
+
+// HashPrefix returns the signature prefix ValidatorFor uses for h, e.g.
+// "sha256=" for crypto.SHA256, "sha3-256=" for crypto.SHA3_256 and "blake2b="
+// for crypto.BLAKE2b_256. Hashes unknown to it are prefixed with their
+// lowercased name.
+func HashPrefix(h crypto.Hash) string {
+	if p, ok := hashPrefixes[h]; ok {
+		return p
+	}
+
+	return strings.ToLower(h.String()) + "="
+}
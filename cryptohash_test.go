@@ -0,0 +1,67 @@
+package hmacsig
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestValidatorFor(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		hash   crypto.Hash
+		prefix string
+		sig    string
+	}{
+		{crypto.SHA1, "sha1=", SignSHA1(body, "EvenDifferentKey")},
+		{crypto.SHA224, "sha224=", SignSHA224(body, "EvenDifferentKey")},
+		{crypto.SHA256, "sha256=", "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"},
+		{crypto.SHA384, "sha384=", SignSHA384(body, "EvenDifferentKey")},
+		{crypto.SHA512, "sha512=", Sign(body, "EvenDifferentKey", crypto.SHA512.New, "sha512=")},
+	}
+
+	for _, tc := range tt {
+		if p := HashPrefix(tc.hash); p != tc.prefix {
+			t.Errorf("expected prefix '%s' for %v; got '%s'", tc.prefix, tc.hash, p)
+		}
+
+		v, err := ValidatorFor(tc.hash)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", tc.hash, err)
+			continue
+		}
+
+		if !v(body, tc.sig, "EvenDifferentKey") {
+			t.Errorf("expected %v to validate '%s'", tc.hash, tc.sig)
+		}
+
+		if v(body, tc.sig, "wrong") {
+			t.Errorf("expected %v not to validate '%s' with the wrong secret", tc.hash, tc.sig)
+		}
+	}
+
+	prefixes := []struct {
+		hash   crypto.Hash
+		prefix string
+	}{
+		{crypto.SHA3_256, "sha3-256="},
+		{crypto.SHA3_512, "sha3-512="},
+		{crypto.SHA512_256, "sha512-256="},
+		{crypto.BLAKE2b_256, "blake2b="},
+		{crypto.BLAKE2b_512, "blake2b-512="},
+		{crypto.BLAKE2s_256, "blake2s="},
+	}
+
+	for _, tc := range prefixes {
+		if p := HashPrefix(tc.hash); p != tc.prefix {
+			t.Errorf("expected prefix '%s' for %v; got '%s'", tc.prefix, tc.hash, p)
+		}
+	}
+
+	for _, h := range []crypto.Hash{crypto.MD4, crypto.Hash(0), crypto.Hash(99)} {
+		if _, err := ValidatorFor(h); !errors.Is(err, ErrHashUnavailable) {
+			t.Errorf("expected ErrHashUnavailable for %v; got %v", h, err)
+		}
+	}
+}
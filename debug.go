@@ -0,0 +1,59 @@
+package hmacsig
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+// ExpectedSignatureHeader is the response header in which the expected
+// signature is returned with OptionDebugExpectedSignature
+const ExpectedSignatureHeader = "X-HMAC-Expected"
+
+// OptionDebugExpectedSignature sets the ExpectedSignatureHeader response
+// header on signature mismatches and malformed signatures to the signature
+// the middleware computed for the body, for the first accepted secret, so that
+// the developer of a new sender can compare it against their own.
+//
+// THIS IS UNSAFE FOR PRODUCTION. Anyone can obtain a valid signature for an
+// arbitrary body by sending it unsigned, defeating the verification entirely.
+// Only enable it in development environments.
+//
+// It applies only where the hash and signature format are known, as
+// configured by Handler, Handler256 or OptionSignaturePrefix. With
+// OptionSecretFunc the secret is resolved a second time.
+func OptionDebugExpectedSignature(mux *hmacSig) {
+	mux.debugExpected = true
+}
+
+// debugsExpected reports whether the expected signature is to be returned on
+// the failure reason
+func (xh *hmacSig) debugsExpected(reason error) bool {
+	return xh.debugExpected && xh.hash != nil && xh.format != nil && len(xh.headerValidators) == 0 &&
+		(errors.Is(reason, ErrSignatureMismatch) || errors.Is(reason, ErrMalformedSignature))
+}
+
+// setExpected sets the expected signature of body on w if configured to
+// by OptionDebugExpectedSignature
+func (xh *hmacSig) setExpected(w http.ResponseWriter, r *http.Request, body []byte, reason error) {
+	if !xh.debugsExpected(reason) {
+		return
+	}
+
+	secrets, err := xh.resolveSecrets(r)
+	if err != nil || len(secrets) == 0 {
+		return
+	}
+
+	w.Header().Set(ExpectedSignatureHeader, Sign(body, secrets[0], xh.hash, xh.format.prefix))
+}
+
+// setStreamExpected sets the expected signature computed by sv on w if
+// configured to by OptionDebugExpectedSignature
+func (xh *hmacSig) setStreamExpected(w http.ResponseWriter, sv *streamVerifier, reason error) {
+	if !xh.debugsExpected(reason) || len(sv.macs) == 0 {
+		return
+	}
+
+	w.Header().Set(ExpectedSignatureHeader, sv.prefix+hex.EncodeToString(sv.macs[0].Sum(nil)))
+}
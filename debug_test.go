@@ -0,0 +1,46 @@
+package hmacsig
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugExpectedSignature(t *testing.T) {
+	body := []byte("This is the body of the request")
+	expected := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+
+	tt := []struct {
+		name     string
+		sig      string
+		options  []Option
+		expected string
+	}{
+		{"not enabled", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", nil, ""},
+		{"mismatch", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionDebugExpectedSignature}, expected},
+		{"malformed", "sha1=zz", []Option{OptionDebugExpectedSignature}, expected},
+		{"missing", "", []Option{OptionDebugExpectedSignature}, ""},
+		{"valid", expected, []Option{OptionDebugExpectedSignature}, ""},
+		{"rotation", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionDebugExpectedSignature, OptionSecrets("supersecret", "old")}, expected},
+		{"custom validator", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionDebugExpectedSignature, OptionSignatureValidator(SHA1Validator)}, ""},
+		{"reuse GetBody", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionDebugExpectedSignature, OptionReuseGetBody}, expected},
+		{"spilled", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionDebugExpectedSignature, OptionSpillToDisk(4, t.TempDir())}, expected},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if h := rec.Header().Get(ExpectedSignatureHeader); h != tc.expected {
+			t.Errorf("%s: expected %s '%s'; got '%s'", tc.name, ExpectedSignatureHeader, tc.expected, h)
+		}
+	}
+}
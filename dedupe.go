@@ -0,0 +1,135 @@
+package hmacsig
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MsgDuplicateDelivery is the message returned in the body when a verified
+// request was a duplicate delivery skipped by OptionDeduper
+const MsgDuplicateDelivery = "Duplicate delivery ignored"
+
+// Deduper records the delivery IDs of verified requests so that deliveries
+// retried by the sender are processed at most once
+type Deduper interface {
+	// Seen records id and reports whether it had already been recorded
+	Seen(ctx context.Context, id string) (bool, error)
+}
+
+// OptionDeduper configures a Deduper consulted with the value of the given
+// delivery ID header, e.g. GithubDeliveryHeader, once a request is verified.
+// Requests whose delivery ID was already seen are passed to the duplicate
+// handler, which by default responds 200 OK without calling the wrapped
+// handler.
+//
+// Unlike OptionNonceStore, which rejects replays as a security measure, this
+// is for at-most-once processing of legitimate retries, and fails open:
+// requests without the header are delivered as usual, as are requests for
+// which the Deduper returns an error, which is logged to the Logger configured
+// by OptionLogger. The delivery ID is recorded before the wrapped handler is
+// called, so a delivery whose processing fails is not processed again if
+// retried.
+func OptionDeduper(header string, d Deduper) Option {
+	return func(mux *hmacSig) {
+		mux.dedupeHeader = header
+		mux.deduper = d
+	}
+}
+
+// OptionDuplicateHandler configures the http.Handler called in place of the
+// wrapped handler for a duplicate delivery, see OptionDeduper
+func OptionDuplicateHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.duplicateHandler = handler
+	}
+}
+
+// DefaultDuplicateHandler is the default response to a duplicate delivery,
+// acknowledging it so that it is not retried again
+func DefaultDuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgDuplicateDelivery, http.StatusOK)
+}
+
+// duplicate reports whether the verified request r is a duplicate delivery
+func (xh *hmacSig) duplicate(r *http.Request) bool {
+	if xh.deduper == nil {
+		return false
+	}
+
+	id := r.Header.Get(xh.dedupeHeader)
+	if id == "" {
+		return false
+	}
+
+	seen, err := xh.deduper.Seen(r.Context(), id)
+	if err != nil {
+		if xh.logger != nil {
+			xh.logger.Printf("hmacsig: deduplication failed, delivering: %v: remote_addr=%q", err, r.RemoteAddr)
+		}
+		return false
+	}
+
+	return seen
+}
+
+// MemoryDeduper is an in-memory Deduper which forgets IDs a TTL after they
+// were last seen and, beyond a maximum number of IDs, evicts the least
+// recently seen. It is safe for concurrent use.
+type MemoryDeduper struct {
+	ttl  time.Duration
+	size int
+
+	mu    sync.Mutex
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+type dedupeEntry struct {
+	id  string
+	exp time.Time
+}
+
+// NewMemoryDeduper returns a MemoryDeduper remembering up to size IDs for ttl.
+// A size of zero or less means no limit.
+func NewMemoryDeduper(ttl time.Duration, size int) *MemoryDeduper {
+	return &MemoryDeduper{
+		ttl:   ttl,
+		size:  size,
+		order: list.New(),
+		ids:   make(map[string]*list.Element),
+	}
+}
+
+// Seen implements Deduper. It never returns an error.
+func (m *MemoryDeduper) Seen(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	// the list is ordered by expiry, so expired IDs are at the back
+	for e := m.order.Back(); e != nil && now.After(e.Value.(*dedupeEntry).exp); e = m.order.Back() {
+		m.remove(e)
+	}
+
+	if e, ok := m.ids[id]; ok {
+		e.Value.(*dedupeEntry).exp = now.Add(m.ttl)
+		m.order.MoveToFront(e)
+		return true, nil
+	}
+
+	m.ids[id] = m.order.PushFront(&dedupeEntry{id, now.Add(m.ttl)})
+	if m.size > 0 && m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+
+	return false, nil
+}
+
+func (m *MemoryDeduper) remove(e *list.Element) {
+	m.order.Remove(e)
+	delete(m.ids, e.Value.(*dedupeEntry).id)
+}
@@ -0,0 +1,129 @@
+package hmacsig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type errDeduper struct{}
+
+func (errDeduper) Seen(ctx context.Context, id string) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func TestDeduper(t *testing.T) {
+	body := []byte("This is the body of the request")
+	valid := SignSHA1(body, "supersecret")
+	invalid := SignSHA1(body, "wrongsecret")
+
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	type delivery struct {
+		id     string
+		sig    string
+		status int
+		called bool
+	}
+
+	tt := []struct {
+		name       string
+		deduper    Deduper
+		options    []Option
+		deliveries []delivery
+	}{
+		{"retry", NewMemoryDeduper(time.Minute, 0), nil, []delivery{
+			{"a", valid, http.StatusOK, true},
+			{"a", valid, http.StatusOK, false},
+			{"b", valid, http.StatusOK, true},
+		}},
+		{"failed first", NewMemoryDeduper(time.Minute, 0), nil, []delivery{
+			{"a", invalid, http.StatusForbidden, false},
+			{"a", valid, http.StatusOK, true},
+			{"a", invalid, http.StatusForbidden, false},
+		}},
+		{"no id", NewMemoryDeduper(time.Minute, 0), nil, []delivery{
+			{"", valid, http.StatusOK, true},
+			{"", valid, http.StatusOK, true},
+		}},
+		{"store error", errDeduper{}, nil, []delivery{
+			{"a", valid, http.StatusOK, true},
+			{"a", valid, http.StatusOK, true},
+		}},
+		{"custom handler", NewMemoryDeduper(time.Minute, 0), []Option{OptionDuplicateHandler(teapot)}, []delivery{
+			{"a", valid, http.StatusOK, true},
+			{"a", valid, http.StatusTeapot, false},
+		}},
+	}
+
+	for _, tc := range tt {
+		options := append([]Option{OptionDeduper(GithubDeliveryHeader, tc.deduper)}, tc.options...)
+
+		called := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.Write([]byte("ok"))
+		})
+		h := Handler(x, "supersecret", options...)
+
+		for i, d := range tc.deliveries {
+			req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+			req.Header.Set(GithubSignatureHeader, d.sig)
+			if d.id != "" {
+				req.Header.Set(GithubDeliveryHeader, d.id)
+			}
+			rec := httptest.NewRecorder()
+
+			called = false
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != d.status {
+				t.Errorf("%s #%d: expected status %d; got %d", tc.name, i, d.status, rec.Code)
+			}
+
+			if called != d.called {
+				t.Errorf("%s #%d: expected handler called %v; got %v", tc.name, i, d.called, called)
+			}
+
+			if !d.called && d.status == http.StatusOK && strings.TrimSpace(rec.Body.String()) != MsgDuplicateDelivery {
+				t.Errorf("%s #%d: expected message '%s'; got '%s'", tc.name, i, MsgDuplicateDelivery, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestMemoryDeduper(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewMemoryDeduper(time.Minute, 2)
+	for _, step := range []struct {
+		id   string
+		seen bool
+	}{
+		{"a", false},
+		{"a", true},
+		{"b", false},
+		{"a", true},
+		{"c", false}, // evicts b, the least recently seen
+		{"a", true},
+		{"b", false},
+	} {
+		if seen, _ := m.Seen(ctx, step.id); seen != step.seen {
+			t.Errorf("expected %s seen %v; got %v", step.id, step.seen, seen)
+		}
+	}
+
+	m = NewMemoryDeduper(10*time.Millisecond, 0)
+	m.Seen(ctx, "a")
+	time.Sleep(20 * time.Millisecond)
+	if seen, _ := m.Seen(ctx, "a"); seen {
+		t.Error("expected a to be forgotten after the TTL")
+	}
+}
@@ -0,0 +1,70 @@
+package hmacsig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MsgKeyDerivation is the message returned in the body when the HMAC key
+// could not be derived by the function configured by OptionKeyDerivation
+const MsgKeyDerivation = "Unable to derive HMAC key"
+
+// ErrKeyDerivation is the failure reason when the function configured by
+// OptionKeyDerivation returned an error. The underlying error is wrapped.
+var ErrKeyDerivation = errors.New("hmacsig: key derivation failed")
+
+// OptionKeyDerivation configures a function deriving the HMAC key for a
+// request from each secret, for instance with HKDF using a channel ID read
+// from a header as the info parameter. The derived key is validated against
+// in place of the secret, by whichever validator is configured.
+//
+// If fn returns an error the request is passed to the key derivation error
+// handler. By default secrets are used as they are.
+func OptionKeyDerivation(fn func(r *http.Request, secret []byte) ([]byte, error)) Option {
+	return func(mux *hmacSig) {
+		mux.keyDerivation = fn
+	}
+}
+
+// OptionKeyDerivationErrorHandler configures the http.Handler called when the
+// function configured by OptionKeyDerivation returns an error
+func OptionKeyDerivationErrorHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.keyDerivationErrorHandler = handler
+	}
+}
+
+// DefaultKeyDerivationErrorHandler is the default response to the HMAC key
+// failing to derive. As derivation typically fails on missing or invalid
+// input from the request, such as a channel ID, it responds 400 Bad Request.
+// The underlying error is not exposed to the client.
+func DefaultKeyDerivationErrorHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgKeyDerivation, http.StatusBadRequest)
+}
+
+// JSONKeyDerivationErrorHandler responds to the HMAC key failing to derive
+// with a JSON body of the form {"error":"..."}
+func JSONKeyDerivationErrorHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgKeyDerivation, http.StatusBadRequest)
+}
+
+// deriveKeys returns the keys derived for r from secrets by the function
+// configured by OptionKeyDerivation, or secrets if none is configured
+func (xh *hmacSig) deriveKeys(r *http.Request, secrets []string) ([]string, error) {
+	if xh.keyDerivation == nil {
+		return secrets, nil
+	}
+
+	keys := make([]string, len(secrets))
+	for i, secret := range secrets {
+		key, err := xh.keyDerivation(r, []byte(secret))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyDerivation, err)
+		}
+
+		keys[i] = string(key)
+	}
+
+	return keys, nil
+}
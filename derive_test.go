@@ -0,0 +1,81 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+func channelKey(r *http.Request, secret []byte) ([]byte, error) {
+	channel := r.Header.Get("X-Channel-Id")
+	if channel == "" {
+		return nil, errors.New("missing channel id")
+	}
+
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(channel)), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func TestKeyDerivation(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	derived := func(channel string) string {
+		r, _ := http.NewRequest("POST", "localhost", nil)
+		r.Header.Set("X-Channel-Id", channel)
+		key, _ := channelKey(r, []byte("supersecret"))
+
+		return SignKey(body, key, sha256.New, "sha256=")
+	}
+
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !errors.Is(ErrorFromContext(r.Context()), ErrKeyDerivation) {
+			t.Errorf("expected reason '%v'; got '%v'", ErrKeyDerivation, ErrorFromContext(r.Context()))
+		}
+
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		channel string
+		sig     string
+		options []Option
+		status  int
+	}{
+		{"chan-1", derived("chan-1"), nil, http.StatusOK},
+		{"chan-2", derived("chan-1"), nil, http.StatusForbidden},
+		{"chan-1", SignSHA256(body, "supersecret"), nil, http.StatusForbidden},
+		{"", derived("chan-1"), nil, http.StatusBadRequest},
+		{"", derived("chan-1"), []Option{OptionKeyDerivationErrorHandler(teapot)}, http.StatusTeapot},
+		{"chan-1", derived("chan-1"), []Option{OptionSpillToDisk(4, t.TempDir())}, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		if tc.channel != "" {
+			req.Header.Set("X-Channel-Id", tc.channel)
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		options := append([]Option{OptionKeyDerivation(channelKey)}, tc.options...)
+		Handler256(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for channel '%s'; got %d", tc.status, tc.channel, rec.Code)
+		}
+	}
+}
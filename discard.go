@@ -0,0 +1,15 @@
+package hmacsig
+
+// OptionDiscardBody configures the middleware not to restore the body of
+// verified requests, for wrapped handlers which act on the headers alone. The
+// body is consumed by verification, so the wrapped handler's r.Body is
+// http.NoBody and r.GetBody nil.
+//
+// This is a performance trade-off: the buffered body is released once the
+// request is verified rather than retained for the lifetime of the request,
+// and with OptionSpillToDisk its temporary file is removed before the wrapped
+// handler is called. Body taps and OptionBodyContext are unaffected. By default
+// the body is restored in full.
+func OptionDiscardBody(mux *hmacSig) {
+	mux.discardBody = true
+}
@@ -0,0 +1,57 @@
+package hmacsig
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscardBody(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		options  []Option
+		expected []byte
+	}{
+		{nil, body},
+		{[]Option{OptionDiscardBody}, []byte{}},
+		{[]Option{OptionDiscardBody, OptionSpillToDisk(4, "")}, []byte{}},
+		{[]Option{OptionDiscardBody, OptionBodyContext}, []byte{}},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		rec := httptest.NewRecorder()
+
+		executed := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			executed = true
+
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if !bytes.Equal(b, tc.expected) {
+				t.Errorf("expected body '%s'; got '%s'", tc.expected, b)
+			}
+
+			if (r.GetBody == nil) != (len(tc.expected) == 0) {
+				t.Errorf("unexpected GetBody %v", r.GetBody != nil)
+			}
+
+			if cb, ok := BodyFromContext(r.Context()); ok && !bytes.Equal(cb, body) {
+				t.Errorf("expected context body '%s'; got '%s'", body, cb)
+			}
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if !executed {
+			t.Errorf("expected handler to be executed; got %v", rec.Result().Status)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package hmacsig
+
+import (
+	"strings"
+)
+
+// DefaultPrefixValidators returns a new map of the built-in validators keyed
+// by the algorithm named in their signature prefix, suitable for passing to
+// PrefixDispatchValidator after adding any others.
+func DefaultPrefixValidators() map[string]SignatureValidator {
+	return map[string]SignatureValidator{
+		"sha1":   SHA1Validator,
+		"sha224": SHA224Validator,
+		"sha256": SHA256Validator,
+		"sha384": SHA384Validator,
+	}
+}
+
+// PrefixDispatchValidator returns a SignatureValidator which selects the
+// validator to apply from the algorithm named before the "=" of the
+// signature, e.g. "sha256" for "sha256=...". The algorithm is matched
+// case-insensitively against the keys of validators. Signatures naming an
+// unknown algorithm, or none at all, fail validation.
+//
+// The validators map is copied, so later changes to it have no effect.
+func PrefixDispatchValidator(validators map[string]SignatureValidator) SignatureValidator {
+	vs := make(map[string]SignatureValidator, len(validators))
+	for algo, v := range validators {
+		vs[strings.ToLower(algo)] = v
+	}
+
+	return func(body []byte, sig, secret string) bool {
+		algo, _, ok := strings.Cut(sig, "=")
+		if !ok {
+			return false
+		}
+
+		v, ok := vs[strings.ToLower(algo)]
+		if !ok {
+			return false
+		}
+
+		return v(body, sig, secret)
+	}
+}
+
+// OptionPrefixDispatch configures the SignatureValidator to be selected from
+// the algorithm prefix of each signature, see PrefixDispatchValidator. If
+// validators is nil, DefaultPrefixValidators is used.
+func OptionPrefixDispatch(validators map[string]SignatureValidator) Option {
+	if validators == nil {
+		validators = DefaultPrefixValidators()
+	}
+
+	return OptionSignatureValidator(PrefixDispatchValidator(validators))
+}
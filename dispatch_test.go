@@ -0,0 +1,52 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrefixDispatch(t *testing.T) {
+	body := []byte("This body is super")
+
+	custom := DefaultPrefixValidators()
+	custom["hmac-sha256"] = NewValidator(sha256.New, "hmac-sha256=")
+
+	tt := []struct {
+		sig        string
+		validators map[string]SignatureValidator
+		status     int
+	}{
+		{SignSHA1(body, "supersecret"), nil, http.StatusOK},
+		{SignSHA256(body, "supersecret"), nil, http.StatusOK},
+		{SignSHA384(body, "supersecret"), nil, http.StatusOK},
+		{SignSHA224(body, "supersecret"), nil, http.StatusOK},
+		{"SHA256=" + SignSHA256(body, "supersecret")[7:], nil, http.StatusOK},
+		{SignSHA256(body, "wrongsecret"), nil, http.StatusForbidden},
+		{Sign(body, "supersecret", sha256.New, "md5="), nil, http.StatusForbidden},
+		{Sign(body, "supersecret", sha256.New, ""), nil, http.StatusForbidden},
+		{Sign(body, "supersecret", sha256.New, "hmac-sha256="), nil, http.StatusForbidden},
+		{Sign(body, "supersecret", sha256.New, "hmac-sha256="), custom, http.StatusOK},
+		{SignSHA1(body, "supersecret"), map[string]SignatureValidator{"sha256": SHA256Validator}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", OptionPrefixDispatch(tc.validators))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for '%v'; got %v", tc.status, tc.sig, res.Status)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package hmacsig
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// Encoding is the textual encoding of a signature digest
+type Encoding int
+
+const (
+	// EncodingHex is lowercase or uppercase hexadecimal, as used by GitHub
+	EncodingHex Encoding = iota
+
+	// EncodingBase64 is standard padded base64, as used by Shopify
+	EncodingBase64
+
+	// EncodingBase64URL is URL-safe base64 using - and _. Padding is optional.
+	EncodingBase64URL
+)
+
+// encode returns the digest d encoded with enc
+func (enc Encoding) encode(d []byte) string {
+	switch enc {
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(d)
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(d)
+	}
+
+	return hex.EncodeToString(d)
+}
+
+// decode returns the digest encoded in s, or an error if s is not valid in
+// the alphabet of enc
+func (enc Encoding) decode(s string) ([]byte, error) {
+	switch enc {
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+
+	return hex.DecodeString(s)
+}
+
+// maxDecodedDigest is the size of the stack buffers signatures are decoded
+// into, that of the largest digest of the hashes this package provides
+const maxDecodedDigest = 64
+
+// decodeTo is decode, decoding hex into the capacity of dst where it suffices
+// rather than allocating. It accepts and rejects exactly what decode does.
+func (enc Encoding) decodeTo(dst []byte, s string) ([]byte, error) {
+	if enc == EncodingBase64 || enc == EncodingBase64URL || len(s)%2 != 0 || len(s)/2 > cap(dst) {
+		return enc.decode(s)
+	}
+
+	d := dst[:len(s)/2]
+	for i := range d {
+		hi, ok1 := fromHexChar(s[2*i])
+		lo, ok2 := fromHexChar(s[2*i+1])
+		if !ok1 || !ok2 {
+			return enc.decode(s)
+		}
+		d[i] = hi<<4 | lo
+	}
+
+	return d, nil
+}
+
+// fromHexChar converts a hex character into its value
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+
+	return 0, false
+}
+
+// NewEncodedValidator returns a SignatureValidator for signatures consisting
+// of prefix followed by the HMAC computed with h, encoded with enc. The
+// decoded digest is compared in constant time, and signatures which do not
+// decode with enc are rejected.
+//
+// Signatures for the returned SignatureValidator may be created with
+// SignEncoded.
+func NewEncodedValidator(h func() hash.Hash, prefix string, enc Encoding) SignatureValidator {
+	v := NewEncodedValidatorE(h, prefix, enc)
+
+	return func(body []byte, sig, secret string) bool {
+		return v(body, sig, secret) == nil
+	}
+}
+
+// NewEncodedValidatorE is the SignatureValidatorE counterpart of
+// NewEncodedValidator
+func NewEncodedValidatorE(h func() hash.Hash, prefix string, enc Encoding) SignatureValidatorE {
+	m := newHMACHash(h)
+
+	return func(body []byte, sig, secret string) error {
+		return validateKey(body, sig, []byte(secret), m, prefix, enc)
+	}
+}
+
+// SignEncoded computes the HMAC of body with the given secret and hash,
+// returning the digest encoded with enc preceded by prefix. It is the
+// counterpart of NewEncodedValidator.
+func SignEncoded(body []byte, secret string, h func() hash.Hash, prefix string, enc Encoding) string {
+	return prefix + enc.encode(digest(body, secret, h))
+}
@@ -0,0 +1,63 @@
+package hmacsig
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"testing"
+)
+
+func TestEncodedValidator(t *testing.T) {
+	tt := []struct {
+		h      func() hash.Hash
+		prefix string
+		enc    Encoding
+		sig    string
+		secret string
+		body   string
+		err    error
+	}{
+		{sha256.New, "sha256=", EncodingHex, "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", "This body is super", nil},
+		{sha256.New, "", EncodingBase64, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW/LG4Bg84LmyM=", "EvenDifferentKey", "This body is super", nil},
+		{sha256.New, "", EncodingBase64URL, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW_LG4Bg84LmyM", "EvenDifferentKey", "This body is super", nil},
+		{sha256.New, "", EncodingBase64URL, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW_LG4Bg84LmyM=", "EvenDifferentKey", "This body is super", nil},
+		{sha1.New, "sha1=", EncodingBase64, "sha1=Defb5C3+9u0x2dDUN0yWIgnlM5w=", "supersecret", "This is the body of the request", nil},
+		{sha1.New, "sha1=", EncodingBase64URL, "sha1=Defb5C3-9u0x2dDUN0yWIgnlM5w", "supersecret", "This is the body of the request", nil},
+
+		{sha256.New, "", EncodingBase64, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW/LG4Bg84LmyM=", "WrongKey", "This body is super", ErrSignatureMismatch},
+		{sha256.New, "", EncodingBase64URL, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW/LG4Bg84LmyM", "EvenDifferentKey", "This body is super", ErrSignatureEncoding},
+		{sha256.New, "", EncodingBase64, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW_LG4Bg84LmyM=", "EvenDifferentKey", "This body is super", ErrSignatureEncoding},
+		{sha256.New, "", EncodingHex, "gU5Qpgz5tO7Q4o760MgB212T1MwPQcW/LG4Bg84LmyM=", "EvenDifferentKey", "This body is super", ErrSignatureEncoding},
+		{sha256.New, "", EncodingBase64, "gU5Qpgz5tO7Q4o76", "EvenDifferentKey", "This body is super", ErrSignatureLength},
+		{sha1.New, "sha1=", EncodingBase64, "Defb5C3+9u0x2dDUN0yWIgnlM5w=", "supersecret", "This is the body of the request", ErrSignaturePrefix},
+	}
+
+	for _, tc := range tt {
+		err := NewEncodedValidatorE(tc.h, tc.prefix, tc.enc)([]byte(tc.body), tc.sig, tc.secret)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("expected error '%v' for '%v'; got '%v'", tc.err, tc.sig, err)
+		}
+
+		if v := NewEncodedValidator(tc.h, tc.prefix, tc.enc)([]byte(tc.body), tc.sig, tc.secret); v != (tc.err == nil) {
+			t.Errorf("expected valid %v for '%v'; got %v", tc.err == nil, tc.sig, v)
+		}
+	}
+}
+
+func TestSignEncoded(t *testing.T) {
+	tt := []struct {
+		enc  Encoding
+		want string
+	}{
+		{EncodingHex, "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"},
+		{EncodingBase64, "sha256=gU5Qpgz5tO7Q4o760MgB212T1MwPQcW/LG4Bg84LmyM="},
+		{EncodingBase64URL, "sha256=gU5Qpgz5tO7Q4o760MgB212T1MwPQcW_LG4Bg84LmyM"},
+	}
+
+	for _, tc := range tt {
+		if got := SignEncoded([]byte("This body is super"), "EvenDifferentKey", sha256.New, "sha256=", tc.enc); got != tc.want {
+			t.Errorf("expected '%s'; got '%s'", tc.want, got)
+		}
+	}
+}
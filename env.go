@@ -0,0 +1,29 @@
+package hmacsig
+
+import (
+	"fmt"
+	"os"
+)
+
+// OptionSecretFromEnv configures the secret as the value of the environment
+// variable name, replacing the secret passed to Handler, which may then be
+// empty. The variable is read once, when the handler is constructed; later
+// changes to it have no effect.
+//
+// If the variable is unset or empty NewHandler returns an error wrapping
+// ErrEmptySecret. Handler, which does not check its configuration, instead
+// rejects every request.
+func OptionSecretFromEnv(name string) Option {
+	return func(mux *hmacSig) {
+		mux.secretNames = nil
+
+		secret := os.Getenv(name)
+		if secret == "" {
+			mux.secrets = nil
+			mux.configErr = fmt.Errorf("%w: environment variable %q is unset or empty", ErrEmptySecret, name)
+			return
+		}
+
+		mux.secrets = []string{secret}
+	}
+}
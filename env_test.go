@@ -0,0 +1,73 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecretFromEnv(t *testing.T) {
+	t.Setenv("HMACSIG_TEST_SECRET", "supersecret")
+	t.Setenv("HMACSIG_TEST_EMPTY", "")
+
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"HMACSIG_TEST_SECRET", nil, http.StatusOK},
+		{"HMACSIG_TEST_EMPTY", ErrEmptySecret, http.StatusForbidden},
+		{"HMACSIG_TEST_UNSET", ErrEmptySecret, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		_, err := NewHandler(x, "", OptionSecretFromEnv(tc.name))
+		if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+			t.Errorf("expected error '%v' for %s; got '%v'", tc.err, tc.name, err)
+		}
+
+		if tc.err != nil && !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected error wrapping '%v'; got '%v'", ErrInvalidConfig, err)
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		rec := httptest.NewRecorder()
+
+		Handler(x, "", OptionSecretFromEnv(tc.name)).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %s; got %d", tc.status, tc.name, rec.Code)
+		}
+	}
+}
+
+func TestSecretFromEnvOnce(t *testing.T) {
+	t.Setenv("HMACSIG_TEST_SECRET", "supersecret")
+
+	body := []byte("This is the body of the request")
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	h := MustHandler(x, "", OptionSecretFromEnv("HMACSIG_TEST_SECRET"))
+	t.Setenv("HMACSIG_TEST_SECRET", "othersecret")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the secret read at construction; got status %d", rec.Code)
+	}
+}
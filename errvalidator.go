@@ -0,0 +1,75 @@
+package hmacsig
+
+import (
+	"hash"
+	"net/http"
+)
+
+// SignatureValidatorE validates the body of a request against the requests
+// signature and servers secret, returning nil if the signature is valid.
+//
+// The returned error describes why validation failed. Errors wrapping
+// ErrMalformedSignature, such as ErrSignaturePrefix, ErrSignatureEncoding and
+// ErrSignatureLength, are passed to the malformed signature handler; all others
+// to the verify failed handler. Either way the error is available to the
+// handler via ErrorFromContext.
+type SignatureValidatorE func(body []byte, sig, secret string) error
+
+// errorFunc adapts the SignatureValidatorE to a validatorFunc
+func (v SignatureValidatorE) errorFunc() validatorFunc {
+	if v == nil {
+		return nil
+	}
+
+	return func(r *http.Request, body []byte, sig, secret string) error {
+		return v(body, sig, secret)
+	}
+}
+
+// AdaptValidator adapts a SignatureValidator to a SignatureValidatorE,
+// reporting ErrSignatureMismatch when it returns false
+func AdaptValidator(v SignatureValidator) SignatureValidatorE {
+	return func(body []byte, sig, secret string) error {
+		if !v(body, sig, secret) {
+			return ErrSignatureMismatch
+		}
+
+		return nil
+	}
+}
+
+// OptionSignatureValidatorE configures the HMAC SignatureValidatorE
+// validated against, in place of the SignatureValidator.
+//
+// As with OptionSignatureValidator, this clears the signature format; the
+// SignatureValidatorE is expected to report malformed signatures itself.
+func OptionSignatureValidatorE(validator SignatureValidatorE) Option {
+	return func(mux *hmacSig) {
+		mux.validator = validator.errorFunc()
+		mux.format = nil
+		mux.hash = nil
+	}
+}
+
+// NewValidatorE returns a SignatureValidatorE for signatures consisting of
+// prefix followed by the hex encoded HMAC computed with h. It is the
+// SignatureValidatorE counterpart of NewValidator.
+func NewValidatorE(h func() hash.Hash, prefix string) SignatureValidatorE {
+	m := newHMACHash(h)
+
+	return func(body []byte, sig, secret string) error {
+		return validateE(body, sig, secret, m, prefix)
+	}
+}
+
+// SHA1ValidatorE implements the interface SignatureValidatorE and
+// SHA-1 HMAC validation
+func SHA1ValidatorE(body []byte, sig, secret string) error {
+	return validateE(body, sig, secret, sha1HMAC, "sha1=")
+}
+
+// SHA256ValidatorE implements the interface SignatureValidatorE and
+// SHA-256 HMAC validation
+func SHA256ValidatorE(body []byte, sig, secret string) error {
+	return validateE(body, sig, secret, sha256HMAC, "sha256=")
+}
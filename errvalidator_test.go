@@ -0,0 +1,92 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignatureValidatorE(t *testing.T) {
+	tt := []struct {
+		sig       string
+		secret    string
+		validator SignatureValidatorE
+		err       error
+	}{
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", SHA256ValidatorE, nil},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", NewValidatorE(sha256.New, "sha256="), nil},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", AdaptValidator(SHA256Validator), nil},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "WrongKey", SHA256ValidatorE, ErrSignatureMismatch},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "WrongKey", AdaptValidator(SHA256Validator), ErrSignatureMismatch},
+		{"sha1=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", SHA256ValidatorE, ErrSignaturePrefix},
+		{"sha256=not-hex", "EvenDifferentKey", SHA256ValidatorE, ErrSignatureEncoding},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db", "EvenDifferentKey", SHA256ValidatorE, ErrSignatureLength},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", SHA1ValidatorE, ErrSignaturePrefix},
+	}
+
+	for _, tc := range tt {
+		err := tc.validator([]byte("This body is super"), tc.sig, tc.secret)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("expected error '%v' for '%v'; got '%v'", tc.err, tc.sig, err)
+		}
+	}
+
+	for _, err := range []error{ErrSignaturePrefix, ErrSignatureEncoding, ErrSignatureLength} {
+		if !errors.Is(err, ErrMalformedSignature) {
+			t.Errorf("expected '%v' to wrap ErrMalformedSignature", err)
+		}
+	}
+}
+
+func TestOptionSignatureValidatorE(t *testing.T) {
+	tt := []struct {
+		sig    string
+		status int
+		err    error
+	}{
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", http.StatusOK, nil},
+		{"sha256=0000000000000000000000000000000000000000000000000000000000000000", http.StatusForbidden, ErrSignatureMismatch},
+		{"sha1=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", http.StatusBadRequest, ErrSignaturePrefix},
+		{"sha256=not-hex", http.StatusBadRequest, ErrSignatureEncoding},
+		{"sha256=814e50a6", http.StatusBadRequest, ErrSignatureLength},
+		{"", http.StatusForbidden, ErrMissingSignature},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This body is super")))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("success"))
+		})
+
+		var got error
+		record := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ErrorFromContext(r.Context())
+				next.ServeHTTP(w, r)
+			})
+		}
+
+		xhs := Handler(x, "EvenDifferentKey",
+			OptionHeader(GithubSignatureHeader256),
+			OptionSignatureValidatorE(SHA256ValidatorE),
+			OptionMissingSignatureHandler(record(http.HandlerFunc(DefaultMissingSignatureHandler))),
+			OptionMalformedSignatureHandler(record(http.HandlerFunc(DefaultMalformedSignatureHandler))),
+			OptionVerifyFailedHandler(record(http.HandlerFunc(DefaultVerifyFailedHandler))),
+		)
+		xhs.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%v'; got %d", tc.status, tc.sig, rec.Code)
+		}
+
+		if !errors.Is(got, tc.err) || (got == nil) != (tc.err == nil) {
+			t.Errorf("expected reason '%v' for '%v'; got '%v'", tc.err, tc.sig, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package hmacsig_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/donatj/hmacsig"
+)
+
+func ExampleMiddleware() {
+	mw := hmacsig.Middleware("supersecret", hmacsig.OptionDefaultsSHA256)
+
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	}))
+
+	body := "This is the body of the request"
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	req.Header.Set(hmacsig.GithubSignatureHeader256, hmacsig.SignSHA256([]byte(body), "supersecret"))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	fmt.Println(rec.Code, rec.Body.String())
+	// Output: 200 success
+}
+
+func ExampleOptionSkipPaths() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("healthy"))
+	})
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	})
+
+	// verify every route of the mux except the health check
+	h := hmacsig.Middleware("supersecret",
+		hmacsig.OptionDefaultsSHA256,
+		hmacsig.OptionSkipPaths("/healthz"),
+	)(mux)
+
+	for _, path := range []string{"/healthz", "/webhook"} {
+		req := httptest.NewRequest("POST", path, strings.NewReader("unsigned"))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		fmt.Println(path, rec.Code)
+	}
+	// Output:
+	// /healthz 200
+	// /webhook 403
+}
@@ -0,0 +1,91 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzSHA256Validator(f *testing.F) {
+	f.Add([]byte("This body is super"), "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey")
+	f.Add([]byte(""), "sha256=", "")
+	f.Add([]byte("body"), "sha256=abc", "secret")
+	f.Add([]byte("body"), "SHA256=ZZ", "secret")
+	f.Add([]byte("body"), "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "secret")
+	f.Add([]byte("body"), " sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23\n", "secret")
+	f.Add([]byte("body"), "sha256="+strings.Repeat("f", 4096), "secret")
+	f.Add([]byte("body"), "t=1492774577,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd", "secret")
+
+	stripe := NewStripeValidator(0)
+	validators := []SignatureValidator{SHA1Validator, SHA256Validator, SHA256Base64Validator, TokenValidator, stripe, NewStripeValidator(time.Minute)}
+
+	f.Fuzz(func(t *testing.T, body []byte, sig, secret string) {
+		for _, v := range validators {
+			v(body, sig, secret)
+		}
+
+		if err := SHA256ValidatorE(body, sig, secret); err == nil && !SHA256Validator(body, sig, secret) {
+			t.Errorf("SHA256ValidatorE accepted %q but SHA256Validator rejected it", sig)
+		}
+
+		if s := SignSHA256(body, secret); !SHA256Validator(body, s, secret) {
+			t.Errorf("expected %q to validate body %q", s, body)
+		}
+
+		if s := SignStripe(body, secret, time.Unix(1492774577, 0)); !stripe(body, s, secret) {
+			t.Errorf("expected %q to validate body %q", s, body)
+		}
+	})
+}
+
+func FuzzParseSignature(f *testing.F) {
+	f.Add("sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "sha256=")
+	f.Add("sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339", "sha1=")
+	f.Add("SHA256=gA==", "sha256=")
+	f.Add("v1,gA", "v1,")
+	f.Add("", "")
+	f.Add("=", "=")
+	f.Add("\t\n", "sha256=")
+	f.Add("keyid=primary,sig=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "sig=")
+	f.Add("sig=,keyid=,keyid=x", "")
+	f.Add("sha256="+strings.Repeat("A", 8192)+"==", "sha256=")
+
+	encodings := []Encoding{EncodingHex, EncodingBase64, EncodingBase64URL, Encoding(-1)}
+
+	f.Fuzz(func(t *testing.T, sig, prefix string) {
+		for _, enc := range encodings {
+			d, err := decodeSignature(sig, prefix, enc)
+
+			var buf [maxDecodedDigest]byte
+			bd, berr := decodeSignatureTo(buf[:0], sig, prefix, enc)
+			if !bytes.Equal(d, bd) || err != berr {
+				t.Errorf("expected decoding into a buffer to match with encoding %d; got %x %v, want %x %v", enc, bd, berr, d, err)
+			}
+
+			if err != nil {
+				continue
+			}
+
+			rd, err := decodeSignature(prefix+enc.encode(d), prefix, enc)
+			if err != nil || !bytes.Equal(d, rd) {
+				t.Errorf("expected %x to round trip with encoding %d; got %x %v", d, enc, rd, err)
+			}
+		}
+
+		if a := declaredAlgorithm(sig); a != "" && !strings.HasPrefix(sig, a) {
+			t.Errorf("expected declared algorithm %q to prefix %q", a, sig)
+		}
+
+		if id, d, ok := parseKeyID(sig); ok {
+			NewKeyIDValidator(sha256.New, func(string) (string, bool) {
+				return prefix, true
+			})([]byte(prefix), sig, "")
+
+			if id == "" || d == "" {
+				t.Errorf("expected key ID and digest for %q; got %q %q", sig, id, d)
+			}
+		}
+	})
+}
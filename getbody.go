@@ -0,0 +1,79 @@
+package hmacsig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// OptionReuseGetBody verifies requests which provide a GetBody, such as those
+// built in-process by http.NewRequest, by streaming the body through the HMAC
+// rather than buffering it, then calling GetBody to supply the body to the
+// wrapped handler. This avoids holding a second copy of large bodies.
+//
+// GetBody must reproduce the body exactly; what it returns is not verified.
+// Requests without a GetBody, which includes every request received by an
+// http.Server, are buffered as usual. Like OptionSpillToDisk it applies only
+// when the hash and signature format are known, and not with
+// OptionBodyContext.
+func OptionReuseGetBody(mux *hmacSig) {
+	mux.reuseGetBody = true
+}
+
+// canReuseGetBody reports whether r can be verified without buffering its body
+func (xh *hmacSig) canReuseGetBody(r *http.Request) bool {
+	return xh.reuseGetBody && r.GetBody != nil && r.Body != nil && r.Body != http.NoBody &&
+		xh.canStream() && !xh.bodyContext && !xh.reportOnly
+}
+
+// serveGetBody verifies the request by streaming its body through the HMAC,
+// passing the body supplied by GetBody to the wrapped handler
+func (xh *hmacSig) serveGetBody(w http.ResponseWriter, r *http.Request) {
+	sv, err := xh.newStreamVerifier(r)
+	if err != nil {
+		xh.fail(w, r, err)
+		return
+	}
+
+	var n int64
+	err = xh.timedRead(r, func(body io.Reader) (err error) {
+		if xh.maxBodyBytes > 0 {
+			body = io.LimitReader(body, xh.maxBodyBytes+1)
+		}
+		n, err = io.Copy(sv, body)
+		return err
+	})
+	if errors.Is(err, errCanceled) {
+		return
+	}
+	if errors.Is(err, ErrReadTimeout) {
+		xh.fail(w, r, err)
+		return
+	}
+	if err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
+		return
+	}
+
+	if xh.maxBodyBytes > 0 && n > xh.maxBodyBytes {
+		xh.fail(w, r, ErrBodyTooLarge)
+		return
+	}
+
+	v, err := sv.verify()
+	r = withBodySize(r, n)
+	if err != nil {
+		xh.setStreamExpected(w, sv, err)
+		xh.fail(w, r, err)
+		return
+	}
+
+	rc, err := r.GetBody()
+	if err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
+		return
+	}
+
+	xh.pass(w, r, v, rc, r.GetBody)
+}
@@ -0,0 +1,63 @@
+package hmacsig
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReuseGetBody(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name     string
+		header   string
+		sig      string
+		options  []Option
+		getBody  bool
+		status   int
+		getCalls int
+	}{
+		{"reused", GithubSignatureHeader, SignSHA1(body, "supersecret"), []Option{OptionReuseGetBody}, true, http.StatusOK, 1},
+		{"reused, invalid", GithubSignatureHeader, SignSHA1(body, "wrongsecret"), []Option{OptionReuseGetBody}, true, http.StatusForbidden, 0},
+		{"reused, too large", GithubSignatureHeader, SignSHA1(body, "supersecret"), []Option{OptionReuseGetBody, OptionMaxBodyBytes(10)}, true, http.StatusRequestEntityTooLarge, 0},
+		{"reused, sha256", GithubSignatureHeader256, SignSHA256(body, "supersecret"), []Option{OptionDefaultsSHA256, OptionReuseGetBody}, true, http.StatusOK, 1},
+		{"no GetBody", GithubSignatureHeader, SignSHA1(body, "supersecret"), []Option{OptionReuseGetBody}, false, http.StatusOK, 0},
+		{"not enabled", GithubSignatureHeader, SignSHA1(body, "supersecret"), nil, true, http.StatusOK, 0},
+		{"opaque validator", GithubSignatureHeader, SignSHA1(body, "supersecret"), []Option{OptionReuseGetBody, OptionSignatureValidator(SHA1Validator)}, true, http.StatusOK, 0},
+	}
+
+	for _, tc := range tt {
+		// ContentLength is left unknown so the body is not rejected early
+		req, _ := http.NewRequest("POST", "localhost", io.NopCloser(bytes.NewReader(body)))
+		req.Header.Set(tc.header, tc.sig)
+
+		getCalls := 0
+		if tc.getBody {
+			req.GetBody = func() (io.ReadCloser, error) {
+				getCalls++
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if !bytes.Equal(b, body) {
+				t.Errorf("%s: expected body '%s'; got '%s'", tc.name, body, b)
+			}
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if getCalls != tc.getCalls {
+			t.Errorf("%s: expected %d GetBody calls; got %d", tc.name, tc.getCalls, getCalls)
+		}
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/donatj/hmacsig
 
 go 1.18
+
+require golang.org/x/crypto v0.17.0
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,69 @@
+package hmacsig
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OptionDecompressGzip configures requests sent with Content-Encoding: gzip
+// to be decompressed before verification, for senders signing the
+// decompressed body. The wrapped handler receives the decompressed body, with
+// the Content-Encoding and Content-Length headers removed.
+//
+// The limit configured by OptionMaxBodyBytes applies to the decompressed size,
+// guarding against decompression bombs. Bodies which are not valid gzip are
+// passed to the read error handler. Requests with any other Content-Encoding
+// are verified as they are.
+func OptionDecompressGzip(mux *hmacSig) {
+	mux.decompressGzip = true
+}
+
+// withDecompressedBody returns a shallow copy of r whose body is decompressed
+// as it is read, if decompression is configured and r is gzip encoded, or r
+// otherwise
+func (xh *hmacSig) withDecompressedBody(r *http.Request) *http.Request {
+	if !xh.decompressGzip || r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+
+	enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if enc != "gzip" && enc != "x-gzip" {
+		return r
+	}
+
+	r = r.WithContext(r.Context())
+	r.Header = r.Header.Clone()
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	r.Body = &gzipBody{src: r.Body}
+	r.GetBody = nil
+
+	return r
+}
+
+// gzipBody decompresses the request body it wraps, deferring reading the gzip
+// header to the first Read so that it is subject to the read timeout
+type gzipBody struct {
+	src io.ReadCloser
+	zr  *gzip.Reader
+}
+
+func (gb *gzipBody) Read(p []byte) (int, error) {
+	if gb.zr == nil {
+		zr, err := gzip.NewReader(gb.src)
+		if err != nil {
+			return 0, err
+		}
+		gb.zr = zr
+	}
+
+	return gb.zr.Read(p)
+}
+
+// Close closes the underlying body
+func (gb *gzipBody) Close() error {
+	return gb.src.Close()
+}
@@ -0,0 +1,73 @@
+package hmacsig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+
+	return buf.Bytes()
+}
+
+func TestDecompressGzip(t *testing.T) {
+	body := []byte("This is the body of the request")
+	bomb := make([]byte, 1<<20)
+
+	tt := []struct {
+		name     string
+		encoding string
+		payload  []byte
+		sig      string
+		options  []Option
+		status   int
+	}{
+		{"decompressed", "gzip", gzipped(body), SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip}, http.StatusOK},
+		{"x-gzip", "X-Gzip", gzipped(body), SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip}, http.StatusOK},
+		{"spilled", "gzip", gzipped(body), SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip, OptionSpillToDisk(4, t.TempDir())}, http.StatusOK},
+		{"signed compressed", "gzip", gzipped(body), SignSHA256(gzipped(body), "supersecret"), []Option{OptionDecompressGzip}, http.StatusForbidden},
+		{"not configured", "gzip", gzipped(body), SignSHA256(body, "supersecret"), nil, http.StatusForbidden},
+		{"not encoded", "", body, SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip}, http.StatusOK},
+		{"bomb", "gzip", gzipped(bomb), SignSHA256(bomb, "supersecret"), []Option{OptionDecompressGzip, OptionMaxBodyBytes(1024)}, http.StatusRequestEntityTooLarge},
+		{"within limit", "gzip", gzipped(body), SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip, OptionMaxBodyBytes(int64(len(body)))}, http.StatusOK},
+		{"invalid gzip", "gzip", body, SignSHA256(body, "supersecret"), []Option{OptionDecompressGzip}, http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(tc.payload))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		if tc.encoding != "" {
+			req.Header.Set("Content-Encoding", tc.encoding)
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if !bytes.Equal(b, body) {
+				t.Errorf("%s: expected body '%s'; got '%s'", tc.name, body, b)
+			}
+
+			if ce := r.Header.Get("Content-Encoding"); ce != "" {
+				t.Errorf("%s: expected Content-Encoding removed; got '%s'", tc.name, ce)
+			}
+		})
+
+		Handler256(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if tc.encoding != "" && req.Header.Get("Content-Encoding") != tc.encoding {
+			t.Errorf("%s: expected the original request headers untouched", tc.name)
+		}
+	}
+}
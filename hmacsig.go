@@ -1,15 +1,29 @@
 // Package hmacsig implements an HMAC Signature Validation HTTP Middleware
 // for use with the likes of GitHub Webhooks.
+//
+// Signatures are compared in constant time, guarding against an attacker
+// recovering the expected digest for a body of their choosing from the time
+// taken to reject successive guesses. The comparison takes time depending
+// only on the length of the expected digest, regardless of the content or
+// length of the signature sent. Decoding the signature necessarily takes time
+// proportional to its length, but that reveals nothing about the expected
+// digest.
 package hmacsig
 
 import (
 	"bytes"
-	"crypto/hmac"
+	"context"
 	"crypto/sha1"
 	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 )
 
 // Option sets an option of the passed hmacSig
@@ -24,6 +38,14 @@ const (
 	// SHA-256 WebHook signatures
 	GithubSignatureHeader256 = "X-Hub-Signature-256"
 
+	// SignatureHeader384 is the suggested header for SHA-384 signatures,
+	// following GitHub's naming. No major provider has standardized one.
+	SignatureHeader384 = "X-Hub-Signature-384"
+
+	// SignatureHeader224 is the suggested header for SHA-224 signatures,
+	// following GitHub's naming. No major provider has standardized one.
+	SignatureHeader224 = "X-Hub-Signature-224"
+
 	// MsgMissingSignature is the message returned in the body when the
 	// Signature was missing from the request
 	MsgMissingSignature = "Missing required header for HMAC verification"
@@ -31,18 +53,266 @@ const (
 	// MsgFailedHMAC is the message returned in the body when the HMAC did not
 	// Validate as Anticpated.
 	MsgFailedHMAC = "HMAC verification failed"
+
+	// MsgMalformedSignature is the message returned in the body when the
+	// Signature was not in the expected format
+	MsgMalformedSignature = "Malformed HMAC signature"
+
+	// MsgBodyTooLarge is the message returned in the body when the request
+	// body exceeded the configured maximum size
+	MsgBodyTooLarge = "Request body too large"
+
+	// MsgReadError is the message returned in the body when the request body
+	// could not be read
+	MsgReadError = "Unable to read request body"
+
+	// MsgLengthRequired is the message returned in the body when the request
+	// did not declare a Content-Length and OptionRequireContentLength is set
+	MsgLengthRequired = "Content-Length required"
+
+	// MsgSecretLookup is the message returned in the body when the secret
+	// could not be resolved by the function configured by OptionSecretFunc
+	MsgSecretLookup = "Unable to resolve HMAC secret"
+)
+
+var (
+	// ErrMissingSignature is the failure reason when the signature was missing
+	// from the request
+	ErrMissingSignature = errors.New("hmacsig: missing signature")
+
+	// ErrMalformedSignature is the failure reason when the signature was not
+	// in the expected format, e.g. a wrong prefix or invalid hex
+	ErrMalformedSignature = errors.New("hmacsig: malformed signature")
+
+	// ErrSignaturePrefix is the failure reason when the signature did not
+	// start with the expected prefix. It wraps ErrMalformedSignature.
+	ErrSignaturePrefix = fmt.Errorf("%w: bad prefix", ErrMalformedSignature)
+
+	// ErrSignatureEncoding is the failure reason when the signature digest
+	// could not be decoded. It wraps ErrMalformedSignature.
+	ErrSignatureEncoding = fmt.Errorf("%w: bad encoding", ErrMalformedSignature)
+
+	// ErrSignatureLength is the failure reason when the decoded signature
+	// digest was not the length of the expected digest. It wraps
+	// ErrMalformedSignature.
+	ErrSignatureLength = fmt.Errorf("%w: digest length mismatch", ErrMalformedSignature)
+
+	// ErrSignatureMismatch is the failure reason when the signature did not
+	// match the expected HMAC of the body
+	ErrSignatureMismatch = errors.New("hmacsig: signature mismatch")
+
+	// ErrSecretLookup is the failure reason when the function configured by
+	// OptionSecretFunc returned an error or an empty secret, or the request
+	// context was done by the time it returned, or when the SecretStore
+	// configured by OptionSecretStore holds no secrets. The underlying error
+	// is wrapped.
+	ErrSecretLookup = errors.New("hmacsig: secret lookup failed")
+
+	// ErrBodyTooLarge is the failure reason when the request body exceeded the
+	// size configured by OptionMaxBodyBytes
+	ErrBodyTooLarge = errors.New("hmacsig: request body too large")
+
+	// ErrReadBody is the failure reason when the request body could not be
+	// read. The underlying error is wrapped.
+	ErrReadBody = errors.New("hmacsig: unable to read request body")
+
+	// ErrLengthRequired is the failure reason when the request did not declare
+	// a Content-Length and OptionRequireContentLength is set
+	ErrLengthRequired = errors.New("hmacsig: content length required")
+)
+
+var (
+	// ErrInvalidConfig is returned by NewHandler when the handler is
+	// misconfigured. The more specific errors below wrap it.
+	ErrInvalidConfig = errors.New("hmacsig: invalid configuration")
+
+	// ErrNilHandler is returned by NewHandler when the wrapped handler is nil
+	ErrNilHandler = fmt.Errorf("%w: nil handler", ErrInvalidConfig)
+
+	// ErrEmptySecret is returned by NewHandler when a secret is empty and no
+	// secret function or store is configured, or a configured SecretStore
+	// holds no secrets, and by SecretStore.Set for empty secrets
+	ErrEmptySecret = fmt.Errorf("%w: empty secret", ErrInvalidConfig)
+
+	// ErrNoValidator is returned by NewHandler when no validator is configured
+	ErrNoValidator = fmt.Errorf("%w: no validator", ErrInvalidConfig)
 )
 
+// Logger is the interface used to log verification events, satisfied by
+// *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
 type hmacSig struct {
 	h http.Handler
 
-	secret string
-	header string
+	secrets      []string
+	secretNames  []string
+	secretFunc   func(r *http.Request) (string, error)
+	secretStore  *SecretStore
+	keyIDSecrets bool
+	header       string
+
+	missingSignatureHandler     http.Handler
+	malformedSignatureHandler   http.Handler
+	verifyFailedHandler         http.Handler
+	bodyTooLargeHandler         http.Handler
+	readErrorHandler            http.Handler
+	replayHandler               http.Handler
+	lengthRequiredHandler       http.Handler
+	secretErrorHandler          http.Handler
+	noRouteHandler              http.Handler
+	unsupportedMediaTypeHandler http.Handler
+	readTimeoutHandler          http.Handler
+	busyHandler                 http.Handler
+	forbiddenIPHandler          http.Handler
+	bodyTapErrorHandler         http.Handler
+	keyDerivationErrorHandler   http.Handler
+	duplicateHandler            http.Handler
+
+	missingSignatureStatus  int
+	missingSignatureMessage string
+	verifyFailedStatus      int
+	verifyFailedMessage     string
+
+	maxBodyBytes         int64
+	readTimeout          time.Duration
+	readSlots            chan struct{}
+	readSlotWait         time.Duration
+	requireContentLength bool
+	contentTypes         []string
+	skipMethods          []string
+	requireAllMethods    bool
+	skipFunc             func(r *http.Request) bool
+	skipPaths            []string
+
+	allowedIPs        []netip.Prefix
+	trustForwardedFor bool
+	proxyHops         int
+	trustedProxies    []netip.Prefix
+
+	timestampHeader string
+	timestampMaxAge time.Duration
+	now             func() time.Time
+	nonceHeader     string
+	nonceStore      NonceStore
+	dedupeHeader    string
+	deduper         Deduper
+
+	queryParam    string
+	queryFallback bool
+	preferHeader  bool
+	trailer       string
 
-	missingSignatureHandler http.Handler
-	verifyFailedHandler     http.Handler
+	commaSeparated  bool
+	multipleHeaders bool
+	authScheme      string
+	strictAlgorithm bool
+	rejectSHA1Only  bool
 
-	validator SignatureValidator
+	contextHeaders []string
+	bodyContext    bool
+	discardBody    bool
+
+	bodyTap         func(r *http.Request) (io.Writer, error)
+	bodyTapRequired bool
+
+	keyDerivation func(r *http.Request, secret []byte) ([]byte, error)
+
+	spillThreshold int64
+	spillDir       string
+	reuseGetBody   bool
+	verifyOnRead   bool
+	decompressGzip bool
+	bodyNormalizer func(body []byte) []byte
+
+	debugExpected bool
+	reportOnly    bool
+
+	logger           Logger
+	observer         func(result Result, r *http.Request)
+	timedObserver    func(result Result, r *http.Request, d time.Duration)
+	responseObserver func(result Result, r *http.Request, status int, written int64)
+	onVerified       func(r *http.Request)
+	recover          func(w http.ResponseWriter, r *http.Request, recovered interface{})
+
+	validator validatorFunc
+	format    *signatureFormat
+	hash      func() hash.Hash
+
+	headerValidators []HeaderValidator
+	headerFormats    map[string]*signatureFormat
+	requireAll       bool
+	preferFirst      bool
+	checks           []check
+
+	routeConfigs []Route
+	routes       []route
+
+	configErr error
+}
+
+// HeaderValidator pairs an HTTP Header with the SignatureValidator used to
+// validate the signature it holds
+type HeaderValidator struct {
+	Header    string
+	Validator SignatureValidator
+}
+
+// check pairs a header with the validator and optional format applied to it
+type check struct {
+	header    string
+	validator validatorFunc
+	format    *signatureFormat
+}
+
+// validatorFunc is the form every kind of validator is adapted to internally,
+// returning nil if sig is valid and the reason it is not otherwise
+type validatorFunc func(r *http.Request, body []byte, sig, secret string) error
+
+// errorFunc adapts the SignatureValidator to a validatorFunc, reporting
+// ErrSignatureMismatch when it returns false
+func (v SignatureValidator) errorFunc() validatorFunc {
+	if v == nil {
+		return nil
+	}
+
+	return func(r *http.Request, body []byte, sig, secret string) error {
+		if !v(body, sig, secret) {
+			return ErrSignatureMismatch
+		}
+
+		return nil
+	}
+}
+
+// signatureFormat describes the shape of a well formed signature, a prefix
+// followed by the hex encoded digest of the given size. If prefixes is set,
+// any of them is accepted, prefix being the first.
+type signatureFormat struct {
+	prefix   string
+	size     int
+	prefixes []string
+}
+
+func (f *signatureFormat) wellFormed(sig string) bool {
+	var buf [maxDecodedDigest]byte
+	d, err := f.decodeTo(buf[:0], sig)
+	return err == nil && len(d) == f.size
+}
+
+// decodeTo is decodeSignatureTo for whichever prefix of f sig carries
+func (f *signatureFormat) decodeTo(dst []byte, sig string) ([]byte, error) {
+	prefix := f.prefix
+	if len(f.prefixes) > 0 {
+		var ok bool
+		if prefix, ok = matchPrefix(sig, f.prefixes); !ok {
+			return nil, ErrSignaturePrefix
+		}
+	}
+
+	return decodeSignatureTo(dst, sig, prefix, EncodingHex)
 }
 
 // OptionHeader configures the HTTP Header to read for the signature
@@ -59,6 +329,14 @@ func OptionMissingSignatureHandler(handler http.Handler) Option {
 	}
 }
 
+// OptionMalformedSignatureHandler configures the http.Handler called when the
+// signature is not in the expected format, see OptionSignatureFormat
+func OptionMalformedSignatureHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.malformedSignatureHandler = handler
+	}
+}
+
 // OptionVerifyFailedHandler configures the http.Handler called on
 // HMAC verification failure
 func OptionVerifyFailedHandler(handler http.Handler) Option {
@@ -67,18 +345,336 @@ func OptionVerifyFailedHandler(handler http.Handler) Option {
 	}
 }
 
+// OptionMissingSignatureStatus configures the status code the default missing
+// signature handler responds with, e.g. http.StatusUnauthorized. It has no
+// effect if a missing signature handler is configured.
+func OptionMissingSignatureStatus(code int) Option {
+	return func(mux *hmacSig) {
+		mux.missingSignatureStatus = code
+	}
+}
+
+// OptionMissingSignatureMessage configures the message the default missing
+// signature handler responds with in place of MsgMissingSignature, e.g. a
+// localized one. It has no effect if a missing signature handler is
+// configured, including by OptionJSONErrors.
+func OptionMissingSignatureMessage(msg string) Option {
+	return func(mux *hmacSig) {
+		mux.missingSignatureMessage = msg
+	}
+}
+
+// OptionVerifyFailedStatus configures the status code the default verify
+// failed handler responds with. It has no effect if a verify failed handler
+// is configured.
+func OptionVerifyFailedStatus(code int) Option {
+	return func(mux *hmacSig) {
+		mux.verifyFailedStatus = code
+	}
+}
+
+// OptionVerifyFailedMessage configures the message the default verify failed
+// handler responds with in place of MsgFailedHMAC. It has no effect if a
+// verify failed handler is configured, including by OptionJSONErrors.
+func OptionVerifyFailedMessage(msg string) Option {
+	return func(mux *hmacSig) {
+		mux.verifyFailedMessage = msg
+	}
+}
+
+// OptionMaxBodyBytes configures the maximum number of bytes read from the
+// request body. Requests with larger bodies are passed to the body too large
+// handler without being validated. A value of zero or less means no limit.
+func OptionMaxBodyBytes(n int64) Option {
+	return func(mux *hmacSig) {
+		mux.maxBodyBytes = n
+	}
+}
+
+// OptionBodyTooLargeHandler configures the http.Handler called when the
+// request body exceeds the size configured by OptionMaxBodyBytes
+func OptionBodyTooLargeHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.bodyTooLargeHandler = handler
+	}
+}
+
+// OptionRequireContentLength rejects requests that do not declare a
+// Content-Length, such as chunked requests, passing them to the length
+// required handler before the body is read
+func OptionRequireContentLength(mux *hmacSig) {
+	mux.requireContentLength = true
+}
+
+// OptionLengthRequiredHandler configures the http.Handler called when a
+// request without a Content-Length is rejected by OptionRequireContentLength
+func OptionLengthRequiredHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.lengthRequiredHandler = handler
+	}
+}
+
+// OptionReadErrorHandler configures the http.Handler called when the request
+// body could not be read, for instance because the client disconnected
+func OptionReadErrorHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.readErrorHandler = handler
+	}
+}
+
+// OptionSkipMethods configures HTTP methods, compared case-insensitively, for
+// which signature verification is skipped entirely and the request is passed
+// straight to the wrapped handler. By default every request is verified.
+func OptionSkipMethods(methods ...string) Option {
+	return func(mux *hmacSig) {
+		mux.skipMethods = methods
+	}
+}
+
+// OptionRequireAllMethods requires a valid signature on requests of every
+// method, including GET, HEAD and OPTIONS, ignoring any OptionSkipMethods
+// regardless of the order the options are given in, for instance to lock
+// down an endpoint wrapped by helpers which relax verification of bodyless
+// methods. Bodyless requests are verified against the signature of the empty
+// body. Predicates configured by OptionSkip still apply.
+func OptionRequireAllMethods(mux *hmacSig) {
+	mux.requireAllMethods = true
+}
+
+// OptionSkip configures a predicate for which signature verification is
+// skipped entirely when it returns true, e.g. for health or metrics endpoints
+// by path. Skipped requests, body untouched, are passed straight to the
+// wrapped handler. It complements OptionSkipMethods.
+func OptionSkip(fn func(r *http.Request) bool) Option {
+	return func(mux *hmacSig) {
+		mux.skipFunc = fn
+	}
+}
+
+// OptionSkipPaths configures request paths for which signature verification
+// is skipped entirely, e.g. health and readiness endpoints, when the
+// middleware wraps a whole http.ServeMux. As with http.ServeMux, a path ending
+// in a slash skips every path it prefixes, and otherwise only itself. It
+// complements OptionSkip and OptionSkipMethods.
+//
+// To verify only some routes of a mux instead, wrap just their handlers:
+//
+//	mw := Middleware(secret)
+//	mux.Handle("/webhook", mw(webhookHandler))
+func OptionSkipPaths(paths ...string) Option {
+	return func(mux *hmacSig) {
+		mux.skipPaths = paths
+	}
+}
+
+// OptionLogger configures a Logger to which failed verifications are logged,
+// including the failure reason, the remote address and the header checked.
+// Neither secrets nor bodies are logged. By default nothing is logged.
+func OptionLogger(logger Logger) Option {
+	return func(mux *hmacSig) {
+		mux.logger = logger
+	}
+}
+
+// OptionRecover configures a function called when the wrapped handler
+// panics, for instance to respond with a 500. The request passed carries the
+// verification details in its context, see FromContext. http.ErrAbortHandler
+// is always re-panicked.
+//
+// By default panics are not recovered.
+func OptionRecover(fn func(w http.ResponseWriter, r *http.Request, recovered interface{})) Option {
+	return func(mux *hmacSig) {
+		mux.recover = fn
+	}
+}
+
+// OptionSecrets configures the set of secrets a signature is accepted for,
+// replacing the secret passed to Handler. This allows accepting signatures from
+// both an old and a new secret during rotation.
+func OptionSecrets(secrets ...string) Option {
+	return func(mux *hmacSig) {
+		mux.secrets = secrets
+		mux.secretNames = nil
+	}
+}
+
+// NamedSecret pairs a secret with a name identifying it, e.g. "2024-06"
+type NamedSecret struct {
+	Name   string
+	Secret string
+}
+
+// OptionNamedSecrets configures the set of secrets a signature is accepted
+// for, as OptionSecrets does, naming each. The name of the secret a request
+// validated against is reported as Verification.SecretName, available to the
+// wrapped handler and observers via FromContext, so operators can confirm an
+// old secret is no longer in use before retiring it.
+func OptionNamedSecrets(secrets ...NamedSecret) Option {
+	return func(mux *hmacSig) {
+		mux.secrets = make([]string, len(secrets))
+		mux.secretNames = make([]string, len(secrets))
+		for i, s := range secrets {
+			mux.secrets[i] = s.Secret
+			mux.secretNames[i] = s.Name
+		}
+	}
+}
+
+// OptionSecretFunc configures a function used to resolve the secret for each
+// request, for instance from a path segment or tenant header. When set, it
+// takes precedence over the static secret passed to Handler.
+//
+// Lookups against external services should honor r.Context(), which is
+// canceled when the client disconnects. If the function returns an error or
+// an empty secret, or the context is done by the time it returns, the secret
+// error handler is called.
+func OptionSecretFunc(fn func(r *http.Request) (string, error)) Option {
+	return func(mux *hmacSig) {
+		mux.secretFunc = fn
+	}
+}
+
+// OptionSecretErrorHandler configures the http.Handler called when the
+// function configured by OptionSecretFunc fails to resolve a secret
+func OptionSecretErrorHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.secretErrorHandler = handler
+	}
+}
+
 // OptionDefaultsSHA256 configures the HTTP Header and Validator used to the
 // defaults used by GitHub for SHA256 validation
 func OptionDefaultsSHA256(mux *hmacSig) {
 	mux.header = GithubSignatureHeader256
-	mux.validator = SHA256Validator
+	mux.validator = SignatureValidator(SHA256Validator).errorFunc()
+	mux.format = &signatureFormat{prefix: "sha256=", size: sha256.Size}
+	mux.hash = sha256.New
 }
 
 // OptionSignatureValidator configures the HMAC SignatureValidator
-// validated against
+// validated against.
+//
+// As the format expected by a custom SignatureValidator is unknown, this
+// clears the signature format; use OptionSignatureFormat after it to restore
+// detection of malformed signatures.
 func OptionSignatureValidator(validator SignatureValidator) Option {
 	return func(mux *hmacSig) {
-		mux.validator = validator
+		mux.validator = validator.errorFunc()
+		mux.format = nil
+		mux.hash = nil
+	}
+}
+
+// OptionSignaturePrefix configures the prefix, including any separator,
+// expected before the hex encoded digest, e.g. "sha256:" rather than the
+// default "sha256=", or "" for a bare digest. Signatures with any other prefix
+// are rejected.
+//
+// It applies to the SHA-1 or SHA-256 algorithm configured by Handler,
+// Handler256 or OptionDefaultsSHA256 and must follow them. It has no effect
+// following OptionSignatureValidator; use NewValidator to configure the prefix
+// of other algorithms.
+func OptionSignaturePrefix(prefix string) Option {
+	return func(mux *hmacSig) {
+		if mux.hash == nil {
+			return
+		}
+
+		mux.validator = NewValidatorE(mux.hash, prefix).errorFunc()
+		mux.format = &signatureFormat{prefix: prefix, size: mux.hash().Size()}
+	}
+}
+
+// OptionHeaderValidators configures an ordered list of headers and the
+// SignatureValidator for each. The headers are tried in order and the request
+// passes if any of them validates. The missing signature handler is only
+// called when none of the headers are present.
+//
+// When set, this takes precedence over OptionHeader and
+// OptionSignatureValidator, and malformed signatures are treated as failed
+// verifications unless a format is configured with OptionHeaderFormat.
+func OptionHeaderValidators(hvs ...HeaderValidator) Option {
+	return func(mux *hmacSig) {
+		mux.headerValidators = hvs
+		mux.requireAll = false
+		mux.preferFirst = false
+	}
+}
+
+// OptionPreferredHeaderValidators configures an ordered list of headers and
+// the SignatureValidator for each, strongest first. Only the first header
+// present is validated; later headers are never fallen back to if it fails,
+// so stripping the stronger signature is the only way to have the weaker one
+// checked.
+//
+// When set, this takes precedence over OptionHeader and
+// OptionSignatureValidator, and malformed signatures are treated as failed
+// verifications unless a format is configured with OptionHeaderFormat.
+func OptionPreferredHeaderValidators(hvs ...HeaderValidator) Option {
+	return func(mux *hmacSig) {
+		mux.headerValidators = hvs
+		mux.requireAll = false
+		mux.preferFirst = true
+	}
+}
+
+// OptionPreferSHA256 configures validation of GitHub's X-Hub-Signature-256
+// when present, falling back to the SHA-1 X-Hub-Signature only when it is
+// absent. An invalid X-Hub-Signature-256 fails regardless of X-Hub-Signature.
+// Malformed signatures, such as those with invalid hex, are passed to the
+// malformed signature handler.
+func OptionPreferSHA256(mux *hmacSig) {
+	OptionPreferredHeaderValidators(
+		HeaderValidator{GithubSignatureHeader256, SHA256Validator},
+		HeaderValidator{GithubSignatureHeader, SHA1Validator},
+	)(mux)
+	OptionHeaderFormat(GithubSignatureHeader256, "sha256=", sha256.Size)(mux)
+	OptionHeaderFormat(GithubSignatureHeader, "sha1=", sha1.Size)(mux)
+}
+
+// OptionAllHeaderValidators configures a list of headers and the
+// SignatureValidator for each, all of which must be present and validate for
+// the request to pass.
+//
+// If any of the headers is missing the missing signature handler is called.
+// If all are present but any fails to validate the verify failed handler is
+// called.
+//
+// When set, this takes precedence over OptionHeader and
+// OptionSignatureValidator, and malformed signatures are treated as failed
+// verifications unless a format is configured with OptionHeaderFormat.
+func OptionAllHeaderValidators(hvs ...HeaderValidator) Option {
+	return func(mux *hmacSig) {
+		mux.headerValidators = hvs
+		mux.requireAll = true
+		mux.preferFirst = false
+	}
+}
+
+// OptionSignatureFormat configures the expected format of the signature, a
+// prefix such as "sha256=" followed by the hex encoded digest of size bytes.
+//
+// Signatures not matching the format are passed to the malformed signature
+// handler rather than the verify failed handler, distinguishing client bugs
+// from genuine verification failures.
+func OptionSignatureFormat(prefix string, size int) Option {
+	return func(mux *hmacSig) {
+		mux.format = &signatureFormat{prefix: prefix, size: size}
+	}
+}
+
+// OptionHeaderFormat configures the expected format of the signature in the
+// given header of those configured by OptionHeaderValidators and the like, as
+// OptionSignatureFormat does for the single header. Signatures in the header
+// not matching the format, such as those with invalid hex, are passed to the
+// malformed signature handler rather than the verify failed handler.
+func OptionHeaderFormat(header, prefix string, size int) Option {
+	return func(mux *hmacSig) {
+		if mux.headerFormats == nil {
+			mux.headerFormats = make(map[string]*signatureFormat)
+		}
+
+		mux.headerFormats[http.CanonicalHeaderKey(header)] = &signatureFormat{prefix: prefix, size: size}
 	}
 }
 
@@ -87,35 +683,276 @@ func DefaultMissingSignatureHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, MsgMissingSignature, http.StatusForbidden)
 }
 
+// statusHandler responds with msg and code, or def if code is zero
+func statusHandler(msg string, code, def int) http.Handler {
+	if code == 0 {
+		code = def
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, msg, code)
+	})
+}
+
+// DefaultMalformedSignatureHandler is the default response to a signature
+// not in the expected format
+func DefaultMalformedSignatureHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgMalformedSignature, http.StatusBadRequest)
+}
+
 // DefaultVerifyFailedHandler is the default response to HMAC verification failing
 func DefaultVerifyFailedHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, MsgFailedHMAC, http.StatusForbidden)
 }
 
+// DefaultBodyTooLargeHandler is the default response to a request body
+// exceeding the configured maximum size
+func DefaultBodyTooLargeHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgBodyTooLarge, http.StatusRequestEntityTooLarge)
+}
+
+// DefaultLengthRequiredHandler is the default response to a request without a
+// Content-Length being rejected by OptionRequireContentLength
+func DefaultLengthRequiredHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgLengthRequired, http.StatusLengthRequired)
+}
+
+// DefaultSecretErrorHandler is the default response to the secret failing to
+// resolve. The underlying error is not exposed to the client.
+func DefaultSecretErrorHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgSecretLookup, http.StatusInternalServerError)
+}
+
+// JSONMissingSignatureHandler responds to a missing signature with a JSON
+// body of the form {"error":"..."}
+func JSONMissingSignatureHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgMissingSignature, http.StatusForbidden)
+}
+
+// JSONMalformedSignatureHandler responds to a signature not in the expected
+// format with a JSON body of the form {"error":"..."}
+func JSONMalformedSignatureHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgMalformedSignature, http.StatusBadRequest)
+}
+
+// JSONVerifyFailedHandler responds to HMAC verification failing with a JSON
+// body of the form {"error":"..."}
+func JSONVerifyFailedHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgFailedHMAC, http.StatusForbidden)
+}
+
+// JSONBodyTooLargeHandler responds to a request body exceeding the configured
+// maximum size with a JSON body of the form {"error":"..."}
+func JSONBodyTooLargeHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgBodyTooLarge, http.StatusRequestEntityTooLarge)
+}
+
+// OptionJSONErrors configures the JSON variants of the default handlers,
+// responding with application/json bodies rather than plain text
+func OptionJSONErrors(mux *hmacSig) {
+	mux.missingSignatureHandler = http.HandlerFunc(JSONMissingSignatureHandler)
+	mux.malformedSignatureHandler = http.HandlerFunc(JSONMalformedSignatureHandler)
+	mux.verifyFailedHandler = http.HandlerFunc(JSONVerifyFailedHandler)
+	mux.bodyTooLargeHandler = http.HandlerFunc(JSONBodyTooLargeHandler)
+	mux.readErrorHandler = http.HandlerFunc(JSONReadErrorHandler)
+	mux.replayHandler = http.HandlerFunc(JSONReplayHandler)
+	mux.lengthRequiredHandler = http.HandlerFunc(JSONLengthRequiredHandler)
+	mux.secretErrorHandler = http.HandlerFunc(JSONSecretErrorHandler)
+	mux.noRouteHandler = http.HandlerFunc(JSONNoRouteHandler)
+	mux.unsupportedMediaTypeHandler = http.HandlerFunc(JSONUnsupportedMediaTypeHandler)
+	mux.readTimeoutHandler = http.HandlerFunc(JSONReadTimeoutHandler)
+	mux.forbiddenIPHandler = http.HandlerFunc(JSONForbiddenIPHandler)
+	mux.bodyTapErrorHandler = http.HandlerFunc(JSONBodyTapErrorHandler)
+	mux.keyDerivationErrorHandler = http.HandlerFunc(JSONKeyDerivationErrorHandler)
+	mux.busyHandler = http.HandlerFunc(JSONBusyHandler)
+}
+
+// JSONReadErrorHandler responds to the request body not being readable with a
+// JSON body of the form {"error":"..."}
+func JSONReadErrorHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgReadError, http.StatusInternalServerError)
+}
+
+// JSONReplayHandler responds to a request rejected by replay protection with
+// a JSON body of the form {"error":"..."}
+func JSONReplayHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgReplayRejected, http.StatusForbidden)
+}
+
+// JSONLengthRequiredHandler responds to a request without a Content-Length
+// being rejected with a JSON body of the form {"error":"..."}
+func JSONLengthRequiredHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgLengthRequired, http.StatusLengthRequired)
+}
+
+// JSONSecretErrorHandler responds to the secret failing to resolve with a JSON
+// body of the form {"error":"..."}
+func JSONSecretErrorHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgSecretLookup, http.StatusInternalServerError)
+}
+
+func jsonError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+// DefaultReadErrorHandler is the default response to the request body not
+// being readable. The underlying error is not exposed to the client.
+func DefaultReadErrorHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgReadError, http.StatusInternalServerError)
+}
+
 // Handler provides HMAC signature validating middleware.
 //
 // see: https://developer.github.com/webhooks/securing/
 //
 // If no options.Header is provided, GithubSignatureHeader will be used.
+//
+// Requests of every method, including bodyless ones such as GET, HEAD and
+// OPTIONS, must be signed unless OptionSkipMethods or OptionSkip say
+// otherwise; see OptionRequireAllMethods.
+//
+// If the request context is done while the body is being read, as when the
+// client disconnects, reading is abandoned and no response is written.
+//
+// Handler does not check its configuration; see NewHandler.
 func Handler(h http.Handler, secret string, options ...Option) http.Handler {
+	return newHMACSig(h, secret, options...)
+}
+
+// NewHandler is Handler, returning an error wrapping ErrInvalidConfig for
+// obvious misconfigurations: a nil handler, an empty secret without a secret
+// function or store, no validator, or an invalid option argument such as a
+// CIDR block.
+func NewHandler(h http.Handler, secret string, options ...Option) (http.Handler, error) {
+	sig := newHMACSig(h, secret, options...)
+	if err := sig.validateConfig(); err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// MustHandler is NewHandler, panicking with the configuration error if the
+// handler is misconfigured. It is intended for handlers built at startup.
+func MustHandler(h http.Handler, secret string, options ...Option) http.Handler {
+	sig, err := NewHandler(h, secret, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	return sig
+}
+
+func newHMACSig(h http.Handler, secret string, options ...Option) *hmacSig {
 	sig := &hmacSig{
-		h:      h,
-		secret: secret,
-		header: GithubSignatureHeader,
+		h:       h,
+		secrets: []string{secret},
+		header:  GithubSignatureHeader,
+		now:     time.Now,
 
-		missingSignatureHandler: http.HandlerFunc(DefaultMissingSignatureHandler),
-		verifyFailedHandler:     http.HandlerFunc(DefaultVerifyFailedHandler),
+		missingSignatureMessage: MsgMissingSignature,
+		verifyFailedMessage:     MsgFailedHMAC,
 
-		validator: SHA1Validator,
+		malformedSignatureHandler:   http.HandlerFunc(DefaultMalformedSignatureHandler),
+		bodyTooLargeHandler:         http.HandlerFunc(DefaultBodyTooLargeHandler),
+		readErrorHandler:            http.HandlerFunc(DefaultReadErrorHandler),
+		replayHandler:               http.HandlerFunc(DefaultReplayHandler),
+		lengthRequiredHandler:       http.HandlerFunc(DefaultLengthRequiredHandler),
+		secretErrorHandler:          http.HandlerFunc(DefaultSecretErrorHandler),
+		noRouteHandler:              http.HandlerFunc(DefaultNoRouteHandler),
+		unsupportedMediaTypeHandler: http.HandlerFunc(DefaultUnsupportedMediaTypeHandler),
+		readTimeoutHandler:          http.HandlerFunc(DefaultReadTimeoutHandler),
+		forbiddenIPHandler:          http.HandlerFunc(DefaultForbiddenIPHandler),
+		bodyTapErrorHandler:         http.HandlerFunc(DefaultBodyTapErrorHandler),
+		keyDerivationErrorHandler:   http.HandlerFunc(DefaultKeyDerivationErrorHandler),
+		duplicateHandler:            http.HandlerFunc(DefaultDuplicateHandler),
+		busyHandler:                 http.HandlerFunc(DefaultBusyHandler),
+
+		validator: SignatureValidator(SHA1Validator).errorFunc(),
+		format:    &signatureFormat{prefix: "sha1=", size: sha1.Size},
+		hash:      sha1.New,
 	}
 
 	for _, option := range options {
 		option(sig)
 	}
 
+	if sig.missingSignatureHandler == nil {
+		sig.missingSignatureHandler = statusHandler(sig.missingSignatureMessage, sig.missingSignatureStatus, http.StatusForbidden)
+	}
+	if sig.verifyFailedHandler == nil {
+		sig.verifyFailedHandler = statusHandler(sig.verifyFailedMessage, sig.verifyFailedStatus, http.StatusForbidden)
+	}
+
+	sig.checks = []check{{sig.header, sig.validator, sig.format}}
+	if len(sig.headerValidators) > 0 {
+		sig.checks = make([]check, len(sig.headerValidators))
+		for i, hv := range sig.headerValidators {
+			sig.checks[i] = check{
+				header:    hv.Header,
+				validator: hv.Validator.errorFunc(),
+				format:    sig.headerFormats[http.CanonicalHeaderKey(hv.Header)],
+			}
+		}
+	}
+
+	if len(sig.routeConfigs) > 0 {
+		sig.buildRoutes(options)
+	}
+
 	return sig
 }
 
+// validateConfig reports the first obvious misconfiguration of xh
+func (xh *hmacSig) validateConfig() error {
+	if xh.h == nil {
+		return ErrNilHandler
+	}
+
+	if xh.configErr != nil {
+		return xh.configErr
+	}
+
+	if len(xh.routes) > 0 {
+		for _, rt := range xh.routes {
+			if err := rt.sig.validateConfig(); err != nil {
+				return fmt.Errorf("%w (route %q)", err, rt.path)
+			}
+		}
+
+		return nil
+	}
+
+	if xh.secretFunc == nil && xh.secretStore != nil && len(xh.secretStore.Secrets()) == 0 {
+		return ErrEmptySecret
+	}
+
+	if xh.secretFunc == nil && xh.secretStore == nil && !xh.keyIDSecrets {
+		if len(xh.secrets) == 0 {
+			return ErrEmptySecret
+		}
+
+		for _, secret := range xh.secrets {
+			if secret == "" {
+				return ErrEmptySecret
+			}
+		}
+	}
+
+	for _, c := range xh.checks {
+		if c.validator == nil {
+			return ErrNoValidator
+		}
+	}
+
+	return nil
+}
+
 // Handler256 provides HMAC signature validating middleware defaulting to SHA256.
 //
 // Handler256 is a convenience method which invokes Handler while including
@@ -124,6 +961,17 @@ func Handler256(h http.Handler, secret string, options ...Option) http.Handler {
 	return Handler(h, secret, append([]Option{OptionDefaultsSHA256}, options...)...)
 }
 
+// Middleware returns HMAC signature validating middleware in the
+// func(http.Handler) http.Handler form used by middleware stacks such as chi,
+// alice and gorilla.
+//
+// Middleware is a thin wrapper over Handler and behaves identically.
+func Middleware(secret string, options ...Option) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return Handler(h, secret, options...)
+	}
+}
+
 // SignatureValidator validates the body of a request against the requests
 // signature and servers secret
 type SignatureValidator func(body []byte, sig, secret string) bool
@@ -131,48 +979,772 @@ type SignatureValidator func(body []byte, sig, secret string) bool
 // SHA1Validator implements the interface SignatureValidator and
 // SHA-1 HMAC validation
 func SHA1Validator(body []byte, sig, secret string) bool {
-	hash := hmac.New(sha1.New, []byte(secret))
-	hash.Write(body)
-
-	ehash := hash.Sum(nil)
-	esig := "sha1=" + hex.EncodeToString(ehash)
-
-	return hmac.Equal([]byte(esig), []byte(sig))
+	return validate(body, sig, secret, sha1HMAC, "sha1=")
 }
 
 // SHA256Validator implements the interface SignatureValidator and
 // SHA-256 HMAC validation
 func SHA256Validator(body []byte, sig, secret string) bool {
-	hash := hmac.New(sha256.New, []byte(secret))
-	hash.Write(body)
+	return validate(body, sig, secret, sha256HMAC, "sha256=")
+}
+
+// SHA256RawValidator implements the interface SignatureValidator and
+// SHA-256 HMAC validation of a bare hex encoded digest without any prefix,
+// compared case-insensitively
+func SHA256RawValidator(body []byte, sig, secret string) bool {
+	return validate(body, sig, secret, sha256HMAC, "")
+}
+
+// SHA384Validator implements the interface SignatureValidator and
+// SHA-384 HMAC validation of signatures prefixed "sha384=".
+//
+// Use OptionSignatureFormat("sha384=", sha512.Size384) alongside it to
+// detect malformed signatures.
+func SHA384Validator(body []byte, sig, secret string) bool {
+	return validate(body, sig, secret, sha384HMAC, "sha384=")
+}
+
+// SHA224Validator implements the interface SignatureValidator and
+// SHA-224 HMAC validation of signatures prefixed "sha224=".
+//
+// Use OptionSignatureFormat("sha224=", sha256.Size224) alongside it to
+// detect malformed signatures.
+func SHA224Validator(body []byte, sig, secret string) bool {
+	return validate(body, sig, secret, sha224HMAC, "sha224=")
+}
 
-	ehash := hash.Sum(nil)
-	esig := "sha256=" + hex.EncodeToString(ehash)
+// NewValidator returns a SignatureValidator for signatures consisting of
+// prefix, e.g. "sha256=", followed by the hex encoded HMAC computed with h.
+//
+// The prefix is matched case-insensitively. Signatures for the returned
+// SignatureValidator may be created with Sign.
+func NewValidator(h func() hash.Hash, prefix string) SignatureValidator {
+	m := newHMACHash(h)
+
+	return func(body []byte, sig, secret string) bool {
+		return validate(body, sig, secret, m, prefix)
+	}
+}
+
+// validate reports whether sig is the case-insensitive prefix followed by the
+// hex encoded HMAC of body. The decoded digest is compared rather than its
+// textual encoding.
+func validate(body []byte, sig, secret string, m *hmacHash, prefix string) bool {
+	return validateE(body, sig, secret, m, prefix) == nil
+}
+
+// validateE is validate, returning the reason sig is invalid
+func validateE(body []byte, sig, secret string, m *hmacHash, prefix string) error {
+	return validateKey(body, sig, []byte(secret), m, prefix, EncodingHex)
+}
+
+// validateKey is validateE for a secret key supplied as raw bytes and a
+// digest encoded with enc. The string based validators delegate to it.
+func validateKey(body []byte, sig string, key []byte, m *hmacHash, prefix string, enc Encoding) error {
+	var buf [maxDecodedDigest]byte
+	sigDigest, err := decodeSignatureTo(buf[:0], sig, prefix, enc)
+	if err != nil {
+		return err
+	}
+
+	ok, size := m.equal(nil, body, key, sigDigest)
+	if size != len(sigDigest) {
+		return ErrSignatureLength
+	}
+
+	if !ok {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+// asciiSpace is the surrounding whitespace trimmed from signatures, which
+// some clients send with a trailing space or newline
+const asciiSpace = " \t\n\v\f\r"
+
+// decodeSignature strips surrounding whitespace and the case-insensitive prefix
+// from sig and decodes the remainder with enc
+func decodeSignature(sig, prefix string, enc Encoding) ([]byte, error) {
+	return decodeSignatureTo(nil, sig, prefix, enc)
+}
+
+// decodeSignatureTo is decodeSignature, decoding into the capacity of dst
+// where it suffices so that a stack buffer spares an allocation
+func decodeSignatureTo(dst []byte, sig, prefix string, enc Encoding) ([]byte, error) {
+	sig = strings.Trim(sig, asciiSpace)
+	if len(sig) < len(prefix) || !strings.EqualFold(sig[:len(prefix)], prefix) {
+		return nil, ErrSignaturePrefix
+	}
+
+	d, err := enc.decodeTo(dst, sig[len(prefix):])
+	if err != nil {
+		return nil, ErrSignatureEncoding
+	}
 
-	return hmac.Equal([]byte(esig), []byte(sig))
+	return d, nil
 }
 
 func (xh *hmacSig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	b, err := io.ReadAll(r.Body)
+	if xh.responseObserver != nil {
+		rw := &responseWriter{ResponseWriter: w}
+		xh.serveHTTP(rw, r)
+		rw.report(xh.responseObserver)
+		return
+	}
+
+	xh.serveHTTP(w, r)
+}
+
+func (xh *hmacSig) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(xh.routes) > 0 {
+		if rt := xh.route(r); rt != nil {
+			rt.ServeHTTP(w, r)
+		} else if xh.skip(r) {
+			// routes inherit the skips, so only unrouted requests are skipped here
+			xh.serve(w, withDisposition(xh.withContextHeaders(r), DispositionSkipped))
+		} else {
+			xh.fail(w, r, ErrNoRoute)
+		}
+		return
+	}
+
+	r = xh.withStart(r)
+	r = xh.withContextHeaders(r)
+
+	if xh.skip(r) {
+		xh.serve(w, withDisposition(r, DispositionSkipped))
+		return
+	}
+
+	if !xh.ipAllowed(r) {
+		xh.fail(w, r, ErrForbiddenIP)
+		return
+	}
+
+	if xh.sha1Only(r) {
+		xh.fail(w, r, ErrSHA1Only)
+		return
+	}
+
+	if !xh.contentTypeAllowed(r) {
+		xh.fail(w, r, ErrUnsupportedMediaType)
+		return
+	}
+
+	if xh.requireContentLength && r.ContentLength < 0 {
+		xh.fail(w, r, ErrLengthRequired)
+		return
+	}
+
+	r = xh.withDecompressedBody(r)
+
+	if xh.maxBodyBytes > 0 && r.ContentLength > xh.maxBodyBytes {
+		xh.fail(w, r, ErrBodyTooLarge)
+		return
+	}
+
+	if xh.canVerifyOnRead() {
+		xh.serveVerifyOnRead(w, r)
+		return
+	}
+
+	release, err := xh.acquireRead(r)
+	if errors.Is(err, errCanceled) {
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		xh.fail(w, r, err)
 		return
 	}
+	defer release()
 
-	xSig := r.Header.Get(xh.header)
+	if xh.canReuseGetBody(r) {
+		xh.serveGetBody(w, r)
+		return
+	}
 
-	if xSig == "" {
-		xh.missingSignatureHandler.ServeHTTP(w, r)
+	var b []byte
+	err = xh.timedRead(r, func(body io.Reader) (err error) {
+		b, err = xh.readBody(r, body)
+		return err
+	})
+	if errors.Is(err, errCanceled) {
+		return
+	}
+	if errors.Is(err, ErrReadTimeout) {
+		xh.fail(w, r, err)
+		return
+	}
+	if err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
 		return
 	}
 
-	if !xh.validator(b, xSig, xh.secret) {
-		xh.verifyFailedHandler.ServeHTTP(w, r)
+	if xh.maxBodyBytes > 0 && int64(len(b)) > xh.maxBodyBytes {
+		xh.fail(w, r, ErrBodyTooLarge)
 		return
 	}
 
-	r.Body.Close()
-	r.Body = io.NopCloser(bytes.NewBuffer(b))
+	r, b = xh.normalizeBody(r, b)
+	r = xh.withBufferedBody(r, b)
+
+	if xh.spills(b) {
+		xh.serveSpilled(w, r, b)
+		return
+	}
+
+	v, err := xh.verify(r, b)
+	r = withBodySize(r, int64(len(b)))
+	if err != nil {
+		xh.setExpected(w, r, b, err)
+		xh.fail(w, r, err)
+		return
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	if xh.bodyContext {
+		r = r.WithContext(context.WithValue(r.Context(), bodyContextKey, b))
+	}
+
+	xh.pass(w, r, v, io.NopCloser(bytes.NewBuffer(b)), getBody)
+}
+
+// pass applies the checks following successful verification and, if they
+// succeed, calls the wrapped handler with the request body replaced by body
+// and GetBody by getBody
+func (xh *hmacSig) pass(w http.ResponseWriter, r *http.Request, v Verification, body io.ReadCloser, getBody func() (io.ReadCloser, error)) {
+	if err := xh.checkReplay(r); err != nil {
+		xh.fail(w, r, err)
+		return
+	}
+
+	tapErr := xh.tap(r, body, getBody)
+	if tapErr != nil && xh.bodyTapRequired {
+		xh.fail(w, r, tapErr)
+		return
+	}
+
+	v.TapErr = tapErr
+	v.SecretName = xh.secretName(v.SecretIndex)
+	v.BodySize, _ = BodySizeFromContext(r.Context())
+	v.Disposition = DispositionVerified
+	r = r.WithContext(context.WithValue(r.Context(), verificationContextKey, v))
+	xh.observe(w, ResultOK, r)
+
+	if xh.discardBody {
+		body.Close()
+		body, getBody = http.NoBody, nil
+	}
+
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	r.Body = body
+	r.GetBody = getBody
+
+	if xh.duplicate(r) {
+		xh.duplicateHandler.ServeHTTP(w, r)
+		return
+	}
+
+	if xh.onVerified != nil {
+		xh.onVerified(r)
+	}
+
+	xh.serve(w, r)
+}
+
+// serve calls the wrapped handler, recovering from any panic with the
+// function configured by OptionRecover
+func (xh *hmacSig) serve(w http.ResponseWriter, r *http.Request) {
+	if xh.recover != nil {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				xh.recover(w, r, rec)
+			}
+		}()
+	}
 
 	xh.h.ServeHTTP(w, r)
 }
+
+// verify checks the signatures of r against body, trying each configured
+// check in order and passing if any validates
+func (xh *hmacSig) verify(r *http.Request, body []byte) (Verification, error) {
+	if xh.requireAll {
+		return xh.verifyAll(r, body)
+	}
+
+	checks := xh.checks
+	if xh.preferFirst {
+		checks = xh.firstPresent(r)
+	}
+
+	present := false
+	for _, c := range checks {
+		if xh.signature(r, c) != "" {
+			present = true
+		}
+	}
+
+	if !present {
+		return Verification{}, ErrMissingSignature
+	}
+
+	secrets, err := xh.resolveSecrets(r)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	// a malformed signature is only reported if no other reason is found
+	var reason error
+	for _, c := range checks {
+		if xh.signature(r, c) == "" {
+			continue
+		}
+
+		idx, err := xh.matchSignatures(c, r, body, secrets)
+		if idx >= 0 {
+			v := Verification{Header: c.header, SecretIndex: idx}
+			if c.format != nil {
+				v.Prefix = c.format.prefix
+			}
+
+			return v, nil
+		}
+
+		if reason == nil || errors.Is(reason, ErrMalformedSignature) {
+			reason = err
+		}
+	}
+
+	return Verification{}, reason
+}
+
+// matchSignatures returns the index of the first secret any signature
+// supplied for c validates against, or -1 and the reason none did
+func (xh *hmacSig) matchSignatures(c check, r *http.Request, body []byte, secrets []string) (int, error) {
+	// a malformed signature is only reported if no other reason is found
+	reason := ErrMalformedSignature
+	var buf [1]string
+	for _, sig := range xh.signatures(buf[:], r, c) {
+		if c.format != nil && !c.format.wellFormed(sig) {
+			if reason == ErrMalformedSignature {
+				reason = xh.malformed(c, sig)
+			}
+			continue
+		}
+
+		idx, err := match(c.validator, r, body, sig, secrets)
+		if idx >= 0 {
+			return idx, nil
+		}
+
+		if errors.Is(reason, ErrMalformedSignature) {
+			reason = err
+		}
+	}
+
+	return -1, reason
+}
+
+// signatures returns the signatures for c supplied with r: the signature
+// itself, or every instance of the header with OptionMultipleHeaders, each
+// split into the tokens of a comma separated list with OptionCommaSeparated.
+// The capacity of buf is used where it suffices.
+func (xh *hmacSig) signatures(buf []string, r *http.Request, c check) []string {
+	values := append(buf[:0], xh.signature(r, c))
+	if xh.multipleHeaders && xh.readsHeader(r, c) {
+		values = xh.headerSignatures(r, c)
+	}
+
+	if !xh.commaSeparated {
+		return values
+	}
+
+	var sigs []string
+	for _, sig := range values {
+		for _, s := range strings.Split(sig, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				sigs = append(sigs, s)
+			}
+		}
+	}
+
+	return sigs
+}
+
+// signature returns the signature for c supplied with r, read from the
+// header of c, the query parameter configured by OptionQueryParam or the
+// trailer configured by OptionTrailer, with surrounding whitespace trimmed
+func (xh *hmacSig) signature(r *http.Request, c check) string {
+	return strings.Trim(xh.sourceSignature(r, c), asciiSpace)
+}
+
+// sourceSignature returns the signature for c as supplied with r
+func (xh *hmacSig) sourceSignature(r *http.Request, c check) string {
+	if xh.trailer != "" {
+		return r.Trailer.Get(xh.trailer)
+	}
+
+	if xh.queryParam == "" {
+		return xh.headerSignature(r, c)
+	}
+
+	if xh.preferHeader {
+		if sig := xh.headerSignature(r, c); sig != "" {
+			return sig
+		}
+
+		return r.URL.Query().Get(xh.queryParam)
+	}
+
+	sig := r.URL.Query().Get(xh.queryParam)
+	if sig == "" && xh.queryFallback {
+		return xh.headerSignature(r, c)
+	}
+
+	return sig
+}
+
+// readsHeader reports whether the signature for c supplied with r is read
+// from its header rather than the query parameter or trailer
+func (xh *hmacSig) readsHeader(r *http.Request, c check) bool {
+	switch {
+	case xh.trailer != "":
+		return false
+	case xh.queryParam == "":
+		return true
+	case xh.preferHeader:
+		return xh.headerSignature(r, c) != ""
+	}
+
+	return xh.queryFallback && r.URL.Query().Get(xh.queryParam) == ""
+}
+
+// headerSignatures returns the non-empty signatures for c read from every
+// instance of its header, with surrounding whitespace trimmed
+func (xh *hmacSig) headerSignatures(r *http.Request, c check) []string {
+	var sigs []string
+	for _, v := range r.Header.Values(c.header) {
+		if sig := strings.Trim(xh.schemeSignature(v), asciiSpace); sig != "" {
+			sigs = append(sigs, sig)
+		}
+	}
+
+	return sigs
+}
+
+// headerSignature returns the signature for c read from its header, less the
+// scheme configured by OptionAuthorizationScheme. With OptionMultipleHeaders
+// it is read from the first instance of the header holding one, so that the
+// signature is only missing if every instance is empty.
+func (xh *hmacSig) headerSignature(r *http.Request, c check) string {
+	if !xh.multipleHeaders {
+		return xh.schemeSignature(r.Header.Get(c.header))
+	}
+
+	for _, v := range r.Header.Values(c.header) {
+		if sig := xh.schemeSignature(v); strings.Trim(sig, asciiSpace) != "" {
+			return sig
+		}
+	}
+
+	return ""
+}
+
+// schemeSignature returns the header value sig less the scheme configured by
+// OptionAuthorizationScheme, or "" if it is in any other scheme
+func (xh *hmacSig) schemeSignature(sig string) string {
+	if xh.authScheme == "" {
+		return sig
+	}
+
+	scheme, sig, ok := strings.Cut(sig, " ")
+	if !ok || !strings.EqualFold(scheme, xh.authScheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(sig)
+}
+
+// firstPresent returns the first check whose header is present on r, if any
+func (xh *hmacSig) firstPresent(r *http.Request) []check {
+	for i, c := range xh.checks {
+		if xh.signature(r, c) != "" {
+			return xh.checks[i : i+1]
+		}
+	}
+
+	return nil
+}
+
+// verifyAll checks the signatures of r against body, passing only if every
+// configured check is present and validates. The returned Verification
+// describes the first check.
+func (xh *hmacSig) verifyAll(r *http.Request, body []byte) (Verification, error) {
+	for _, c := range xh.checks {
+		if xh.signature(r, c) == "" {
+			return Verification{}, ErrMissingSignature
+		}
+	}
+
+	secrets, err := xh.resolveSecrets(r)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	if len(xh.checks) == 0 {
+		return Verification{}, ErrSignatureMismatch
+	}
+
+	v := Verification{Header: xh.checks[0].header}
+	var reason error
+	for i, c := range xh.checks {
+		idx, err := xh.matchSignatures(c, r, body, secrets)
+		if idx < 0 && reason == nil {
+			reason = err
+		}
+
+		if i == 0 {
+			v.SecretIndex = idx
+		}
+	}
+
+	if reason != nil {
+		return Verification{}, reason
+	}
+
+	return v, nil
+}
+
+// resolveSecrets returns the secrets a signature is accepted for, or the keys
+// derived from them by OptionKeyDerivation
+func (xh *hmacSig) resolveSecrets(r *http.Request) ([]string, error) {
+	secrets, err := xh.lookupSecrets(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return xh.deriveKeys(r, secrets)
+}
+
+// lookupSecrets returns the configured secrets, from the secret function or
+// store if configured
+func (xh *hmacSig) lookupSecrets(r *http.Request) ([]string, error) {
+	if xh.secretFunc == nil {
+		if xh.secretStore != nil {
+			secrets := xh.secretStore.Secrets()
+			if len(secrets) == 0 {
+				return nil, fmt.Errorf("%w: %v", ErrSecretLookup, ErrEmptySecret)
+			}
+
+			return secrets, nil
+		}
+
+		return xh.secrets, nil
+	}
+
+	secret, err := xh.secretFunc(r)
+	if err == nil {
+		err = r.Context().Err()
+	}
+	if err == nil && secret == "" {
+		err = ErrEmptySecret
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSecretLookup, err)
+	}
+
+	return []string{secret}, nil
+}
+
+// secretName returns the name of the secret at idx configured by
+// OptionNamedSecrets, if any
+func (xh *hmacSig) secretName(idx int) string {
+	if xh.secretFunc != nil || xh.secretStore != nil || idx < 0 || idx >= len(xh.secretNames) {
+		return ""
+	}
+
+	return xh.secretNames[idx]
+}
+
+// skip reports whether verification should be skipped for r
+func (xh *hmacSig) skip(r *http.Request) bool {
+	for _, m := range xh.skipMethods {
+		if strings.EqualFold(m, r.Method) && !xh.requireAllMethods {
+			return true
+		}
+	}
+
+	for _, p := range xh.skipPaths {
+		if pathMatches(p, r.URL.Path) {
+			return true
+		}
+	}
+
+	return xh.skipFunc != nil && xh.skipFunc(r)
+}
+
+// fail calls the failure handler for reason with the reason stored in the
+// request context, retrievable via ErrorFromContext
+//
+// With OptionReportOnly, the wrapped handler is called instead if the
+// rejection is waived.
+func (xh *hmacSig) fail(w http.ResponseWriter, r *http.Request, reason error) {
+	waived := xh.waives(reason)
+
+	if xh.logger != nil {
+		if waived {
+			xh.logger.Printf("%v: remote_addr=%q header=%q (report-only, not enforced)", reason, r.RemoteAddr, xh.checkedHeaders())
+		} else {
+			xh.logger.Printf("%v: remote_addr=%q header=%q", reason, r.RemoteAddr, xh.checkedHeaders())
+		}
+	}
+
+	xh.observe(w, resultFor(reason), r)
+
+	r = r.WithContext(context.WithValue(r.Context(), errorContextKey, reason))
+	if waived {
+		xh.serve(w, withDisposition(r, DispositionWaived))
+		return
+	}
+
+	xh.handlerFor(reason).ServeHTTP(w, r)
+}
+
+// handlerFor returns the failure handler for reason
+func (xh *hmacSig) handlerFor(reason error) http.Handler {
+	switch {
+	case errors.Is(reason, ErrMissingSignature):
+		return xh.missingSignatureHandler
+	case errors.Is(reason, ErrMalformedSignature):
+		return xh.malformedSignatureHandler
+	case errors.Is(reason, ErrBodyTooLarge):
+		return xh.bodyTooLargeHandler
+	case errors.Is(reason, ErrReadBody):
+		return xh.readErrorHandler
+	case errors.Is(reason, ErrReadTimeout):
+		return xh.readTimeoutHandler
+	case errors.Is(reason, ErrBusy):
+		return xh.busyHandler
+	case errors.Is(reason, ErrForbiddenIP):
+		return xh.forbiddenIPHandler
+	case errors.Is(reason, ErrBodyTap):
+		return xh.bodyTapErrorHandler
+	case errors.Is(reason, ErrLengthRequired):
+		return xh.lengthRequiredHandler
+	case errors.Is(reason, ErrSecretLookup):
+		return xh.secretErrorHandler
+	case errors.Is(reason, ErrKeyDerivation):
+		return xh.keyDerivationErrorHandler
+	case errors.Is(reason, ErrNoRoute):
+		return xh.noRouteHandler
+	case errors.Is(reason, ErrUnsupportedMediaType):
+		return xh.unsupportedMediaTypeHandler
+	case errors.Is(reason, ErrInvalidTimestamp), errors.Is(reason, ErrStaleTimestamp),
+		errors.Is(reason, ErrMissingDeliveryID), errors.Is(reason, ErrReplayed):
+		return xh.replayHandler
+	}
+
+	return xh.verifyFailedHandler
+}
+
+// checkedHeaders returns the headers signatures are read from, comma separated
+func (xh *hmacSig) checkedHeaders() string {
+	headers := make([]string, len(xh.checks))
+	for i, c := range xh.checks {
+		headers[i] = c.header
+	}
+
+	return strings.Join(headers, ",")
+}
+
+// readBody reads body, the body of r, stopping one byte past the configured
+// maximum so an oversized body can be detected without reading all of it. A
+// nil body or http.NoBody, as sent with Content-Length: 0, is treated as empty
+// without being read, so the HMAC of the empty message is validated against.
+//
+// When spilling to disk is configured, reading stops one byte past the spill
+// threshold, leaving the remainder to serveSpilled.
+func (xh *hmacSig) readBody(r *http.Request, body io.Reader) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	if xh.canSpill() && (xh.maxBodyBytes <= 0 || xh.spillThreshold < xh.maxBodyBytes) {
+		return readAll(io.LimitReader(body, xh.spillThreshold+1), r.ContentLength, xh.spillThreshold+1)
+	}
+
+	if xh.maxBodyBytes <= 0 {
+		return readAll(body, r.ContentLength, maxPreallocBytes)
+	}
+
+	return readAll(io.LimitReader(body, xh.maxBodyBytes+1), r.ContentLength, xh.maxBodyBytes+1)
+}
+
+// maxPreallocBytes bounds the buffer allocated up front for a body from its
+// Content-Length, which the client may overstate
+const maxPreallocBytes = 1 << 20
+
+// readAll is io.ReadAll, allocating its buffer up front for a body of size
+// bytes, at most max, so that a body of the declared Content-Length is read
+// without growing it. A size of zero or less is unknown.
+func readAll(r io.Reader, size, max int64) ([]byte, error) {
+	if size <= 0 {
+		return io.ReadAll(r)
+	}
+
+	if size > max {
+		size = max
+	}
+
+	// one byte spare so the read reporting io.EOF does not grow the buffer
+	b := make([]byte, 0, size+1)
+	for {
+		n, err := r.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return b, err
+		}
+
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+	}
+}
+
+// match returns the index of the first secret sig validates against, or -1
+// and the reason reported for the first secret if none do. Every secret is
+// checked regardless so the time taken does not reveal which of them matched.
+func match(validator validatorFunc, r *http.Request, body []byte, sig string, secrets []string) (int, error) {
+	idx := -1
+	reason := ErrSignatureMismatch
+	for i, secret := range secrets {
+		err := validator(r, body, sig, secret)
+		if err == nil && idx < 0 {
+			idx = i
+		}
+
+		if i == 0 && err != nil {
+			reason = err
+		}
+	}
+
+	if idx < 0 {
+		return -1, reason
+	}
+
+	return idx, nil
+}
@@ -2,11 +2,19 @@ package hmacsig
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestInvalidHeaders(t *testing.T) {
@@ -14,9 +22,13 @@ func TestInvalidHeaders(t *testing.T) {
 		reqHeader string
 		secret    string
 		msg       string
+		status    int
 	}{
-		{"", "", MsgMissingSignature},
-		{"invalid", "xasd", MsgFailedHMAC},
+		{"", "", MsgMissingSignature, http.StatusForbidden},
+		{"invalid", "xasd", MsgMalformedSignature, http.StatusBadRequest},
+		{"sha1=zz7dbe42dfef6ed31d9d0d4374c962209e5339c", "xasd", MsgMalformedSignature, http.StatusBadRequest},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e533", "xasd", MsgMalformedSignature, http.StatusBadRequest},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "xasd", MsgFailedHMAC, http.StatusForbidden},
 	}
 
 	for _, tc := range tt {
@@ -33,8 +45,8 @@ func TestInvalidHeaders(t *testing.T) {
 
 		res := rec.Result()
 
-		if res.StatusCode != http.StatusForbidden {
-			t.Errorf("expected status Forbidden; got %v", res.Status)
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
 		}
 
 		body, _ := io.ReadAll(res.Body)
@@ -56,10 +68,16 @@ func TestValidHMAC(t *testing.T) {
 	}{
 		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "supersecret", "This is the body of the request", "ok", []Option{}},
 		{GithubSignatureHeader, "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "ThisKeyIsAGreatSecretYouShouldNotUseIt", "This is a more different body", "even more ok", []Option{}},
+		{GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c\n", "supersecret", "This is the body of the request", "trailing newline", []Option{}},
+		{GithubSignatureHeader, " sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c \t", "supersecret", "This is the body of the request", "surrounding space", []Option{}},
 
 		{"Funky-Non-Standard-Header", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "ThisKeyIsAGreatSecretYouShouldNotUseIt", "This is a more different body", "even more ok", []Option{OptionHeader("Funky-Non-Standard-Header")}},
 
 		{GithubSignatureHeader256, "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", "This body is super", "the OKest", []Option{OptionDefaultsSHA256}},
+
+		{SignatureHeader384, "sha384=72683405aa30d7ca2285eef0ff72f1f0eb781fdcb9783ab42abc490c04b4882a21333b58d2775600882a8cfbff84b121", "EvenDifferentKey", "This body is super", "ok384", []Option{OptionHeader(SignatureHeader384), OptionSignatureValidator(SHA384Validator), OptionSignatureFormat("sha384=", sha512.Size384)}},
+		{SignatureHeader224, "sha224=24ab5cfb29b127ec7be02744cb0819f117c4661e9db71a43e962d0ac", "EvenDifferentKey", "This body is super", "ok224", []Option{OptionHeader(SignatureHeader224), OptionSignatureValidator(SHA224Validator)}},
+		{"X-Custom", "sha224=24ab5cfb29b127ec7be02744cb0819f117c4661e9db71a43e962d0ac", "EvenDifferentKey", "This body is super", "okcustom", []Option{OptionHeader("X-Custom"), OptionSignatureValidator(NewValidator(sha256.New224, "sha224="))}},
 	}
 
 	for _, tc := range tt {
@@ -97,3 +115,1304 @@ func TestValidHMAC(t *testing.T) {
 		}
 	}
 }
+
+func TestSecretFunc(t *testing.T) {
+	secrets := map[string]string{
+		"tenant-a": "supersecret",
+		"tenant-b": "ThisKeyIsAGreatSecretYouShouldNotUseIt",
+	}
+
+	secretFunc := func(r *http.Request) (string, error) {
+		s, ok := secrets[r.Header.Get("X-Tenant")]
+		if !ok && r.Header.Get("X-Tenant") != "tenant-empty" {
+			return "", errors.New("unknown tenant")
+		}
+		return s, nil
+	}
+
+	tt := []struct {
+		tenant    string
+		reqHeader string
+		body      string
+		status    int
+	}{
+		{"tenant-a", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusOK},
+		{"tenant-b", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "This is a more different body", http.StatusOK},
+		{"tenant-b", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusForbidden},
+		{"tenant-c", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusInternalServerError},
+		{"tenant-empty", SignSHA1([]byte("This is the body of the request"), ""), "This is the body of the request", http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		req.Header.Set("X-Tenant", tc.tenant)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "", OptionSecretFunc(secretFunc))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for %s; got %v", tc.status, tc.tenant, res.Status)
+		}
+	}
+}
+
+func TestSecretFuncContext(t *testing.T) {
+	tt := []struct {
+		cancel bool
+		custom bool
+		status int
+	}{
+		{false, false, http.StatusOK},
+		{true, false, http.StatusInternalServerError},
+		{true, true, http.StatusTeapot},
+	}
+
+	for _, tc := range tt {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		req, _ := http.NewRequestWithContext(ctx, "POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		// cancel mid-lookup, as a disconnecting client would
+		secretFunc := func(r *http.Request) (string, error) {
+			if r.Context() != ctx {
+				t.Errorf("expected the request context")
+			}
+			if tc.cancel {
+				cancel()
+			}
+			return "supersecret", nil
+		}
+
+		options := []Option{OptionSecretFunc(secretFunc)}
+
+		var got error
+		if tc.custom {
+			options = append(options, OptionSecretErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ErrorFromContext(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+			})))
+		}
+
+		xhs := Handler(x, "", options...)
+		xhs.ServeHTTP(rec, req)
+		cancel()
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d; got %d", tc.status, rec.Code)
+		}
+
+		if tc.custom && (!errors.Is(got, ErrSecretLookup) || !strings.Contains(got.Error(), context.Canceled.Error())) {
+			t.Errorf("expected secret lookup error wrapping '%v'; got '%v'", context.Canceled, got)
+		}
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		body      string
+		secrets   []string
+		status    int
+	}{
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", []string{"supersecret", "newsecret"}, http.StatusOK},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", []string{"oldsecret", "supersecret"}, http.StatusOK},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", []string{"oldsecret", "newsecret"}, http.StatusForbidden},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", []string{}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "", OptionSecrets(tc.secrets...))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for %v; got %v", tc.status, tc.secrets, res.Status)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	tt := []struct {
+		body          string
+		max           int64
+		contentLength int64
+		status        int
+	}{
+		{"This is the body of the request", 31, 31, http.StatusOK},
+		{"This is the body of the request", 1024, 31, http.StatusOK},
+		{"This is the body of the request", 0, 31, http.StatusOK},
+		{"This is the body of the request", 30, 31, http.StatusRequestEntityTooLarge},
+		{"This is the body of the request", 30, -1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(tc.body)))
+		req.ContentLength = tc.contentLength
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", OptionMaxBodyBytes(tc.max))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d with max %d; got %v", tc.status, tc.max, res.Status)
+		}
+
+		if tc.status == http.StatusRequestEntityTooLarge {
+			body, _ := io.ReadAll(res.Body)
+			sbody := strings.TrimSpace(string(body))
+			if sbody != MsgBodyTooLarge {
+				t.Errorf("expected message '%v'; got '%v'", MsgBodyTooLarge, sbody)
+			}
+		}
+	}
+}
+
+func TestRequireContentLength(t *testing.T) {
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		contentLength int64
+		options       []Option
+		status        int
+	}{
+		{31, nil, http.StatusOK},
+		{-1, nil, http.StatusOK},
+		{31, []Option{OptionRequireContentLength}, http.StatusOK},
+		{-1, []Option{OptionRequireContentLength}, http.StatusLengthRequired},
+		{-1, []Option{OptionRequireContentLength, OptionLengthRequiredHandler(teapot)}, http.StatusTeapot},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.ContentLength = tc.contentLength
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d with content length %d; got %v", tc.status, tc.contentLength, res.Status)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		options   []Option
+		msg       string
+		status    int
+	}{
+		{"", nil, MsgMissingSignature, http.StatusForbidden},
+		{"invalid", nil, MsgMalformedSignature, http.StatusBadRequest},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", nil, MsgFailedHMAC, http.StatusForbidden},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionRoutes(Route{Path: "/github"})}, MsgNoRoute, http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte{}))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be executed")
+		})
+
+		xhs := Handler(x, "xasd", append([]Option{OptionJSONErrors}, tc.options...)...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
+		}
+
+		if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("expected JSON content type; got '%v'", ct)
+		}
+
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Error != tc.msg {
+			t.Errorf("expected error '%v'; got '%v'", tc.msg, body.Error)
+		}
+	}
+}
+
+func TestStatusOptions(t *testing.T) {
+	custom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		reqHeader string
+		options   []Option
+		msg       string
+		status    int
+	}{
+		{"", []Option{OptionMissingSignatureStatus(http.StatusUnauthorized)}, MsgMissingSignature, http.StatusUnauthorized},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionMissingSignatureStatus(http.StatusUnauthorized)}, MsgFailedHMAC, http.StatusForbidden},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionVerifyFailedStatus(http.StatusUnauthorized)}, MsgFailedHMAC, http.StatusUnauthorized},
+		{"", []Option{OptionMissingSignatureStatus(http.StatusUnauthorized), OptionMissingSignatureHandler(custom)}, "", http.StatusTeapot},
+		{"", []Option{OptionMissingSignatureHandler(custom), OptionMissingSignatureStatus(http.StatusUnauthorized)}, "", http.StatusTeapot},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionVerifyFailedHandler(custom), OptionVerifyFailedStatus(http.StatusUnauthorized)}, "", http.StatusTeapot},
+		{"", []Option{OptionMissingSignatureMessage("Signatur fehlt")}, "Signatur fehlt", http.StatusForbidden},
+		{"", []Option{OptionMissingSignatureMessage("Signatur fehlt"), OptionMissingSignatureStatus(http.StatusUnauthorized)}, "Signatur fehlt", http.StatusUnauthorized},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionMissingSignatureMessage("Signatur fehlt")}, MsgFailedHMAC, http.StatusForbidden},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionVerifyFailedMessage("Signatur ungültig")}, "Signatur ungültig", http.StatusForbidden},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionVerifyFailedHandler(custom), OptionVerifyFailedMessage("Signatur ungültig")}, "", http.StatusTeapot},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte{}))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be executed")
+		})
+
+		xhs := Handler(x, "xasd", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
+		}
+
+		body, _ := io.ReadAll(res.Body)
+		sbody := strings.TrimSpace(string(body))
+		if sbody != tc.msg {
+			t.Errorf("expected message '%v'; got '%v'", tc.msg, sbody)
+		}
+	}
+}
+
+func TestSignatureFormat(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		options   []Option
+		status    int
+	}{
+		{"sha256=abc", []Option{OptionDefaultsSHA256}, http.StatusBadRequest},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionDefaultsSHA256}, http.StatusBadRequest},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", []Option{OptionDefaultsSHA256}, http.StatusForbidden},
+		{"garbage", []Option{OptionSignatureValidator(SHA1Validator)}, http.StatusForbidden},
+		{"garbage", []Option{OptionSignatureValidator(SHA1Validator), OptionSignatureFormat("sha1=", 20)}, http.StatusBadRequest},
+		{"garbage", []Option{OptionMalformedSignatureHandler(http.HandlerFunc(DefaultVerifyFailedHandler))}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This body is super")))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		req.Header.Set(GithubSignatureHeader256, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be executed")
+		})
+
+		xhs := Handler(x, "xasd", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for '%v'; got %v", tc.status, tc.reqHeader, res.Status)
+		}
+	}
+}
+
+func TestSkipMethods(t *testing.T) {
+	tt := []struct {
+		method    string
+		reqHeader string
+		skip      []string
+		status    int
+	}{
+		{"GET", "", []string{"GET"}, http.StatusOK},
+		{"GET", "", []string{"get", "head"}, http.StatusOK},
+		{"HEAD", "", []string{"get", "head"}, http.StatusOK},
+		{"GET", "", []string{}, http.StatusForbidden},
+		{"POST", "", []string{"GET"}, http.StatusForbidden},
+		{"POST", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []string{"GET"}, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", OptionSkipMethods(tc.skip...))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for %s skipping %v; got %v", tc.status, tc.method, tc.skip, res.Status)
+		}
+	}
+}
+
+func TestRequireAllMethods(t *testing.T) {
+	tt := []struct {
+		method  string
+		sig     string
+		options []Option
+		status  int
+	}{
+		{"GET", "", []Option{OptionRequireAllMethods}, http.StatusForbidden},
+		{"HEAD", "", []Option{OptionRequireAllMethods}, http.StatusForbidden},
+		{"OPTIONS", "", []Option{OptionRequireAllMethods}, http.StatusForbidden},
+		{"GET", "", []Option{OptionRequireAllMethods, OptionSkipMethods("GET")}, http.StatusForbidden},
+		{"GET", "", []Option{OptionSkipMethods("GET"), OptionRequireAllMethods}, http.StatusForbidden},
+		{"GET", "", []Option{OptionSkipMethods("GET")}, http.StatusOK},
+		{"GET", SignSHA1([]byte{}, "supersecret"), []Option{OptionSkipMethods("GET"), OptionRequireAllMethods}, http.StatusOK},
+		{"GET", SignSHA1([]byte{}, "wrongsecret"), []Option{OptionRequireAllMethods}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "localhost", nil)
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %s '%s'; got %d", tc.status, tc.method, tc.sig, rec.Code)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	body := "This is the body of the request"
+	skipHealth := func(r *http.Request) bool {
+		return r.URL.Path == "/healthz"
+	}
+
+	tt := []struct {
+		path      string
+		reqHeader string
+		status    int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/hook", "", http.StatusForbidden},
+		{"/hook", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "http://localhost"+tc.path, bytes.NewReader([]byte(body)))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != body {
+				t.Errorf("expected body '%s' for %s; got '%s'", body, tc.path, b)
+			}
+		})
+
+		xhs := Handler(x, "supersecret", OptionSkip(skipHealth))
+		xhs.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %s; got %d", tc.status, tc.path, rec.Code)
+		}
+	}
+}
+
+func TestNilBody(t *testing.T) {
+	req, _ := http.NewRequest("POST", "localhost", nil)
+	req.Body = nil
+	req.Header.Set(GithubSignatureHeader256, SignSHA256([]byte{}, "supersecret"))
+	rec := httptest.NewRecorder()
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if len(b) != 0 {
+			t.Errorf("expected empty body; got '%s'", b)
+		}
+
+		w.Write([]byte("ok"))
+	})
+
+	xhs := Handler256(x, "supersecret")
+	xhs.ServeHTTP(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", res.Status)
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	tt := []struct {
+		name   string
+		body   io.ReadCloser
+		sig    string
+		status int
+	}{
+		{"NoBody", http.NoBody, SignSHA256([]byte{}, "supersecret"), http.StatusOK},
+		{"empty reader", io.NopCloser(bytes.NewReader([]byte{})), SignSHA256([]byte{}, "supersecret"), http.StatusOK},
+		{"nil", nil, SignSHA256(nil, "supersecret"), http.StatusOK},
+		{"wrong secret", http.NoBody, SignSHA256([]byte{}, "wrongsecret"), http.StatusForbidden},
+		{"signed non-empty", http.NoBody, SignSHA256([]byte("body"), "supersecret"), http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", nil)
+		req.Body = tc.body
+		req.ContentLength = 0
+		req.Header.Set("Content-Length", "0")
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if len(b) != 0 {
+				t.Errorf("%s: expected empty body; got '%s'", tc.name, b)
+			}
+		})
+
+		Handler256(x, "supersecret", OptionRequireContentLength).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+	}
+}
+
+func TestEmptyBodyServer(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	srv := httptest.NewServer(Handler256(x, "supersecret"))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL, http.NoBody)
+	req.Header.Set(GithubSignatureHeader256, SignSHA256([]byte{}, "supersecret"))
+
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", res.Status)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		log       string
+	}{
+		{"", `hmacsig: missing signature: remote_addr="192.0.2.1:1234" header="X-Hub-Signature"`},
+		{"garbage", `hmacsig: malformed signature: remote_addr="192.0.2.1:1234" header="X-Hub-Signature"`},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", `hmacsig: signature mismatch: remote_addr="192.0.2.1:1234" header="X-Hub-Signature"`},
+		{SignSHA1([]byte("This is the body of the request"), "xasd"), ""},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte("This is the body of the request")))
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		buf := &bytes.Buffer{}
+		xhs := Handler(x, "xasd", OptionLogger(log.New(buf, "", 0)))
+		xhs.ServeHTTP(rec, req)
+
+		l := strings.TrimSpace(buf.String())
+		if l != tc.log {
+			t.Errorf("expected log '%v'; got '%v'", tc.log, l)
+		}
+
+		if strings.Contains(l, "xasd") || strings.Contains(l, "This is the body") {
+			t.Errorf("expected log not to contain secret or body; got '%v'", l)
+		}
+	}
+}
+
+func TestHeaderValidators(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name     string
+		sha1     string
+		sha256   string
+		status   int
+		verified string
+	}{
+		{"256 present", "", SignSHA256(body, "supersecret"), http.StatusOK, GithubSignatureHeader256},
+		{"both present", SignSHA1(body, "supersecret"), SignSHA256(body, "supersecret"), http.StatusOK, GithubSignatureHeader256},
+		{"only 1 present", SignSHA1(body, "supersecret"), "", http.StatusOK, GithubSignatureHeader},
+		{"only 1 present and invalid", SignSHA1(body, "wrongsecret"), "", http.StatusForbidden, ""},
+		{"neither present", "", "", http.StatusForbidden, ""},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sha1)
+		req.Header.Set(GithubSignatureHeader256, tc.sha256)
+		rec := httptest.NewRecorder()
+
+		var verified string
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := FromContext(r.Context())
+			verified = v.Header
+			w.Write([]byte("ok"))
+		})
+
+		var reason error
+		failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reason = ErrorFromContext(r.Context())
+			http.Error(w, reason.Error(), http.StatusForbidden)
+		})
+
+		xhs := Handler(x, "supersecret",
+			OptionHeaderValidators(
+				HeaderValidator{GithubSignatureHeader256, SHA256Validator},
+				HeaderValidator{GithubSignatureHeader, SHA1Validator},
+			),
+			OptionMissingSignatureHandler(failed),
+			OptionVerifyFailedHandler(failed))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+
+		if verified != tc.verified {
+			t.Errorf("%s: expected verified header '%v'; got '%v'", tc.name, tc.verified, verified)
+		}
+
+		if tc.name == "neither present" && reason != ErrMissingSignature {
+			t.Errorf("%s: expected reason '%v'; got '%v'", tc.name, ErrMissingSignature, reason)
+		}
+	}
+}
+
+func TestHeaderFormat(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name    string
+		sha256  string
+		options []Option
+		status  int
+	}{
+		{"valid", SignSHA256(body, "supersecret"), []Option{OptionHeaderFormat(GithubSignatureHeader256, "sha256=", sha256.Size)}, http.StatusOK},
+		{"invalid hex", "sha256=zzzz", []Option{OptionHeaderFormat(GithubSignatureHeader256, "sha256=", sha256.Size)}, http.StatusBadRequest},
+		{"invalid hex, header case", "sha256=zzzz", []Option{OptionHeaderFormat("x-hub-signature-256", "sha256=", sha256.Size)}, http.StatusBadRequest},
+		{"invalid hex, no format", "sha256=zzzz", nil, http.StatusForbidden},
+		{"mismatch", SignSHA256(body, "wrongsecret"), []Option{OptionHeaderFormat(GithubSignatureHeader256, "sha256=", sha256.Size)}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sha256)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		options := append([]Option{OptionHeaderValidators(HeaderValidator{GithubSignatureHeader256, SHA256Validator})}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+	}
+}
+
+func TestPreferSHA256(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name     string
+		sha1     string
+		sha256   string
+		status   int
+		verified string
+	}{
+		{"both present", SignSHA1(body, "supersecret"), SignSHA256(body, "supersecret"), http.StatusOK, GithubSignatureHeader256},
+		{"256 invalid, 1 valid", SignSHA1(body, "supersecret"), SignSHA256(body, "wrongsecret"), http.StatusForbidden, ""},
+		{"256 malformed, 1 valid", SignSHA1(body, "supersecret"), "garbage", http.StatusBadRequest, ""},
+		{"256 invalid hex, 1 valid", SignSHA1(body, "supersecret"), "sha256=zzzz", http.StatusBadRequest, ""},
+		{"only 1 present and invalid hex", "sha1=zzzz", "", http.StatusBadRequest, ""},
+		{"only 256 present", "", SignSHA256(body, "supersecret"), http.StatusOK, GithubSignatureHeader256},
+		{"only 1 present", SignSHA1(body, "supersecret"), "", http.StatusOK, GithubSignatureHeader},
+		{"only 1 present and invalid", SignSHA1(body, "wrongsecret"), "", http.StatusForbidden, ""},
+		{"neither present", "", "", http.StatusForbidden, ""},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sha1)
+		req.Header.Set(GithubSignatureHeader256, tc.sha256)
+		rec := httptest.NewRecorder()
+
+		var verified string
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := FromContext(r.Context())
+			verified = v.Header
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", OptionPreferSHA256)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+
+		if verified != tc.verified {
+			t.Errorf("%s: expected verified header '%v'; got '%v'", tc.name, tc.verified, verified)
+		}
+	}
+}
+
+func TestAllHeaderValidators(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name   string
+		sha1   string
+		sha256 string
+		reason error
+	}{
+		{"both valid", SignSHA1(body, "supersecret"), SignSHA256(body, "supersecret"), nil},
+		{"only 256 present", "", SignSHA256(body, "supersecret"), ErrMissingSignature},
+		{"only 1 present", SignSHA1(body, "supersecret"), "", ErrMissingSignature},
+		{"neither present", "", "", ErrMissingSignature},
+		{"1 invalid", SignSHA1(body, "wrongsecret"), SignSHA256(body, "supersecret"), ErrSignatureMismatch},
+		{"256 invalid", SignSHA1(body, "supersecret"), SignSHA256(body, "wrongsecret"), ErrSignatureMismatch},
+		{"1 missing and 256 invalid", "", SignSHA256(body, "wrongsecret"), ErrMissingSignature},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sha1)
+		req.Header.Set(GithubSignatureHeader256, tc.sha256)
+		rec := httptest.NewRecorder()
+
+		executed := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			executed = true
+		})
+
+		var reason error
+		failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reason = ErrorFromContext(r.Context())
+		})
+
+		xhs := Handler(x, "supersecret",
+			OptionAllHeaderValidators(
+				HeaderValidator{GithubSignatureHeader256, SHA256Validator},
+				HeaderValidator{GithubSignatureHeader, SHA1Validator},
+			),
+			OptionMissingSignatureHandler(failed),
+			OptionVerifyFailedHandler(failed))
+		xhs.ServeHTTP(rec, req)
+
+		if executed != (tc.reason == nil) {
+			t.Errorf("%s: expected handler executed %v; got %v", tc.name, tc.reason == nil, executed)
+		}
+
+		if reason != tc.reason {
+			t.Errorf("%s: expected reason '%v'; got '%v'", tc.name, tc.reason, reason)
+		}
+	}
+}
+
+func TestValidatorDecodedComparison(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		sig       string
+		validator SignatureValidator
+		valid     bool
+	}{
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", SHA256Validator, true},
+		{"SHA256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", SHA256Validator, true},
+		{"sha256=814E50A60CF9B4EED0E28EFAD0C801DB5D93D4CC0F41C5BF2C6E0183CE0B9B23", SHA256Validator, true},
+		{"SHA1=" + SignSHA1(body, "EvenDifferentKey")[5:], SHA1Validator, true},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23\r\n", SHA256Validator, true},
+		{"sha256:814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", SHA256Validator, false},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b2", SHA256Validator, false},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b", SHA256Validator, false},
+		{"sha256=zz4e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", SHA256Validator, false},
+		{"sha256=", SHA256Validator, false},
+		{"sha", SHA256Validator, false},
+		{"", SHA256Validator, false},
+	}
+
+	for _, tc := range tt {
+		if v := tc.validator(body, tc.sig, "EvenDifferentKey"); v != tc.valid {
+			t.Errorf("expected %v for '%v'; got %v", tc.valid, tc.sig, v)
+		}
+	}
+}
+
+func TestSignaturePrefix(t *testing.T) {
+	body := []byte("This body is super")
+	digest := "814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"
+
+	tt := []struct {
+		reqHeader string
+		options   []Option
+		status    int
+	}{
+		{"sha256:" + digest, []Option{OptionSignaturePrefix("sha256:")}, http.StatusOK},
+		{"sha256=" + digest, []Option{OptionSignaturePrefix("sha256:")}, http.StatusBadRequest},
+		{digest, []Option{OptionSignaturePrefix("")}, http.StatusOK},
+		{"sha256=" + digest, []Option{OptionSignaturePrefix("")}, http.StatusBadRequest},
+		{"sha256:" + digest, []Option{OptionSignatureValidator(NewValidator(sha256.New, "sha256:"))}, http.StatusOK},
+		{digest, []Option{OptionSignatureValidator(NewValidator(sha256.New, ""))}, http.StatusOK},
+		{"sha256=" + digest, []Option{OptionSignatureValidator(NewValidator(sha256.New, ""))}, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler256(x, "EvenDifferentKey", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for '%v'; got %v", tc.status, tc.reqHeader, res.Status)
+		}
+	}
+}
+
+func TestSHA256RawValidator(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		sig   string
+		valid bool
+	}{
+		{"814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", true},
+		{"814E50A60CF9B4EED0E28EFAD0C801DB5D93D4CC0F41C5BF2C6E0183CE0B9B23", true},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", false},
+		{"914e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", false},
+		{"", false},
+	}
+
+	for _, tc := range tt {
+		if v := SHA256RawValidator(body, tc.sig, "EvenDifferentKey"); v != tc.valid {
+			t.Errorf("expected %v for '%v'; got %v", tc.valid, tc.sig, v)
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("X-Signature", tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "EvenDifferentKey", OptionHeader("X-Signature"), OptionSignatureValidator(SHA256RawValidator))
+		xhs.ServeHTTP(rec, req)
+
+		if ok := rec.Result().StatusCode == http.StatusOK; ok != tc.valid {
+			t.Errorf("expected handler pass %v for '%v'; got %v", tc.valid, tc.sig, ok)
+		}
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	})
+
+	secretFunc := func(r *http.Request) (string, error) {
+		return "supersecret", nil
+	}
+
+	tt := []struct {
+		h       http.Handler
+		secret  string
+		options []Option
+		err     error
+	}{
+		{x, "supersecret", nil, nil},
+		{x, "", []Option{OptionSecrets("supersecret", "oldsecret")}, nil},
+		{x, "", []Option{OptionSecretFunc(secretFunc)}, nil},
+		{nil, "supersecret", nil, ErrNilHandler},
+		{x, "", nil, ErrEmptySecret},
+		{x, "supersecret", []Option{OptionSecrets()}, ErrEmptySecret},
+		{x, "supersecret", []Option{OptionSecrets("supersecret", "")}, ErrEmptySecret},
+		{x, "supersecret", []Option{OptionSignatureValidator(nil)}, ErrNoValidator},
+		{x, "supersecret", []Option{OptionRequestValidator(nil)}, ErrNoValidator},
+		{x, "supersecret", []Option{OptionHeaderValidators(HeaderValidator{GithubSignatureHeader, nil})}, ErrNoValidator},
+	}
+
+	for i, tc := range tt {
+		h, err := NewHandler(tc.h, tc.secret, tc.options...)
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("%d: expected error '%v'; got '%v'", i, tc.err, err)
+		}
+
+		if err != nil {
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("%d: expected '%v' to wrap ErrInvalidConfig", i, err)
+			}
+			continue
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%d: expected status %d; got %d", i, http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func TestMustHandler(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tt := []struct {
+		h      http.Handler
+		secret string
+		err    error
+	}{
+		{x, "supersecret", nil},
+		{nil, "supersecret", ErrNilHandler},
+		{x, "", ErrEmptySecret},
+	}
+
+	for _, tc := range tt {
+		func() {
+			defer func() {
+				rec := recover()
+				if tc.err == nil {
+					if rec != nil {
+						t.Errorf("expected no panic; got '%v'", rec)
+					}
+					return
+				}
+
+				err, ok := rec.(error)
+				if !ok || !errors.Is(err, tc.err) {
+					t.Errorf("expected panic with '%v'; got '%v'", tc.err, rec)
+				}
+			}()
+
+			MustHandler(tc.h, tc.secret)
+		}()
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		sig    string
+		status int
+	}{
+		{SignSHA256(body, "supersecret"), http.StatusOK},
+		{SignSHA256(body, "wrongsecret"), http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Middleware("supersecret", OptionDefaultsSHA256)(x)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for '%v'; got %v", tc.status, tc.sig, res.Status)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestMiddlewareServeMux(t *testing.T) {
+	body := []byte("This body is super")
+	sig := SignSHA256(body, "supersecret")
+
+	newMux := func(paths ...string) *http.ServeMux {
+		mux := http.NewServeMux()
+		for _, p := range paths {
+			mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+		}
+
+		return mux
+	}
+
+	mw := Middleware("supersecret", OptionDefaultsSHA256, OptionSkipPaths("/healthz", "/ready/"))
+	whole := mw(newMux("/healthz", "/ready/", "/webhook", "/public"))
+
+	selective := newMux("/healthz", "/ready/", "/public")
+	selective.Handle("/webhook", Middleware("supersecret", OptionDefaultsSHA256)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+
+	tt := []struct {
+		name   string
+		h      http.Handler
+		method string
+		path   string
+		sig    string
+		status int
+	}{
+		{"whole", whole, "GET", "/healthz", "", http.StatusOK},
+		{"whole", whole, "GET", "/ready/db", "", http.StatusOK},
+		{"whole", whole, "GET", "/healthz/extra", "", http.StatusForbidden},
+		{"whole", whole, "POST", "/webhook", "", http.StatusForbidden},
+		{"whole", whole, "POST", "/webhook", sig, http.StatusOK},
+		{"whole", whole, "POST", "/public", "", http.StatusForbidden},
+		{"selective", selective, "GET", "/healthz", "", http.StatusOK},
+		{"selective", selective, "POST", "/public", "", http.StatusOK},
+		{"selective", selective, "POST", "/webhook", "", http.StatusForbidden},
+		{"selective", selective, "POST", "/webhook", sig, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+		if tc.sig != "" {
+			req.Header.Set(GithubSignatureHeader256, tc.sig)
+		}
+		rec := httptest.NewRecorder()
+
+		tc.h.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d for %s %s; got %d", tc.name, tc.status, tc.method, tc.path, rec.Code)
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := ErrorFromContext(r.Context()); !errors.Is(err, ErrReadBody) {
+			t.Errorf("expected reason '%v'; got '%v'", ErrReadBody, err)
+		}
+
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		options []Option
+		status  int
+		msg     string
+	}{
+		{[]Option{}, http.StatusInternalServerError, MsgReadError},
+		{[]Option{OptionReadErrorHandler(teapot)}, http.StatusTeapot, ""},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", errReader{})
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be executed")
+		})
+
+		xhs := Handler(x, "supersecret", tc.options...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
+		}
+
+		body, _ := io.ReadAll(res.Body)
+		sbody := strings.TrimSpace(string(body))
+		if sbody != tc.msg {
+			t.Errorf("expected message '%v'; got '%v'", tc.msg, sbody)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	rec := httptest.NewRecorder()
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("downstream exploded")
+	})
+
+	var recovered interface{}
+	xhs := Handler(x, "supersecret", OptionRecover(func(w http.ResponseWriter, r *http.Request, rec interface{}) {
+		recovered = rec
+
+		if _, ok := FromContext(r.Context()); !ok {
+			t.Error("expected verification in context")
+		}
+
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	xhs.ServeHTTP(rec, req)
+
+	if recovered != "downstream exploded" {
+		t.Errorf("expected recovered 'downstream exploded'; got '%v'", recovered)
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d; got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestRecoverDefault(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("downstream exploded")
+	})
+
+	defer func() {
+		if rec := recover(); rec != "downstream exploded" {
+			t.Errorf("expected panic 'downstream exploded'; got '%v'", rec)
+		}
+	}()
+
+	Handler(x, "supersecret").ServeHTTP(httptest.NewRecorder(), req)
+	t.Error("expected panic to propagate")
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected panic '%v'; got '%v'", http.ErrAbortHandler, rec)
+		}
+	}()
+
+	Handler(x, "supersecret", OptionRecover(func(w http.ResponseWriter, r *http.Request, rec interface{}) {
+		t.Error("should not be executed")
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGetBody(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("stale")), nil
+	}
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	rec := httptest.NewRecorder()
+
+	executed := false
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		executed = true
+
+		if r.GetBody == nil {
+			t.Fatal("expected GetBody to be set")
+		}
+
+		io.ReadAll(r.Body)
+
+		for i := 0; i < 2; i++ {
+			rc, err := r.GetBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, _ := io.ReadAll(rc)
+			if !bytes.Equal(b, body) {
+				t.Errorf("expected GetBody to return '%s'; got '%s'", body, b)
+			}
+		}
+	})
+
+	Handler(x, "supersecret").ServeHTTP(rec, req)
+
+	if !executed {
+		t.Errorf("expected handler to be executed; got %v", rec.Result().Status)
+	}
+}
+
+// discardResponse is an http.ResponseWriter discarding everything written,
+// for benchmarks where an httptest.ResponseRecorder would dominate
+type discardResponse http.Header
+
+func (d discardResponse) Header() http.Header       { return http.Header(d) }
+func (discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+func (discardResponse) WriteHeader(int)             {}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	small := []byte("This body is super")
+	large := bytes.Repeat([]byte("This body is super large. "), 4096)
+
+	bb := []struct {
+		name    string
+		body    []byte
+		secret  string
+		options []Option
+	}{
+		{"small/valid", small, "supersecret", nil},
+		{"small/invalid", small, "wrongsecret", nil},
+		{"small/timeout", small, "supersecret", []Option{OptionReadTimeout(time.Minute)}},
+		{"large/valid", large, "supersecret", nil},
+		{"large/invalid", large, "wrongsecret", nil},
+		{"large/timeout", large, "supersecret", []Option{OptionReadTimeout(time.Minute)}},
+	}
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, bc := range bb {
+		b.Run(bc.name, func(b *testing.B) {
+			xhs := Handler(x, "supersecret", append([]Option{OptionDefaultsSHA256}, bc.options...)...)
+			sig := SignSHA256(bc.body, bc.secret)
+			body := bytes.NewReader(bc.body)
+			w := discardResponse(http.Header{})
+
+			// a cancelable context, as every server request has
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, _ := http.NewRequestWithContext(ctx, "POST", "localhost", body)
+			req.Header.Set(GithubSignatureHeader256, sig)
+
+			b.SetBytes(int64(len(bc.body)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				body.Reset(bc.body)
+				req.Body = io.NopCloser(body)
+				xhs.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 100)
+
+	tt := []struct {
+		size int64
+		max  int64
+	}{
+		{0, maxPreallocBytes},
+		{-1, maxPreallocBytes},
+		{1000, maxPreallocBytes},
+		{10, maxPreallocBytes},
+		{999, maxPreallocBytes},
+		{1001, maxPreallocBytes},
+		{1 << 40, maxPreallocBytes},
+		{1000, 100},
+	}
+
+	for _, tc := range tt {
+		b, err := readAll(iotest.OneByteReader(bytes.NewReader(body)), tc.size, tc.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(b, body) {
+			t.Errorf("expected body read in full with size %d; got %d bytes", tc.size, len(b))
+		}
+	}
+
+	errRead := errors.New("read failed")
+	if _, err := readAll(iotest.ErrReader(errRead), 10, maxPreallocBytes); !errors.Is(err, errRead) {
+		t.Errorf("expected read error; got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package hmacsigblake2b provides hmacsig validation of HMAC-BLAKE2b-256
+// signatures. It is kept separate from hmacsig so that only users of BLAKE2b
+// build golang.org/x/crypto.
+package hmacsigblake2b
+
+import (
+	"hash"
+
+	"github.com/donatj/hmacsig"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Prefix is the prefix of BLAKE2b-256 signatures
+const Prefix = "blake2b="
+
+// New256 returns an unkeyed BLAKE2b-256 hash, for use with hmacsig.NewValidator
+// and hmacsig.Sign. HMAC provides the keying.
+func New256() hash.Hash {
+	h, _ := blake2b.New256(nil) // errors only for oversized keys
+
+	return h
+}
+
+// BLAKE2bValidator implements the interface hmacsig.SignatureValidator and
+// BLAKE2b-256 HMAC validation of signatures prefixed "blake2b=".
+//
+// Use hmacsig.OptionSignatureFormat(Prefix, blake2b.Size256) alongside it to
+// detect malformed signatures.
+var BLAKE2bValidator = hmacsig.NewValidator(New256, Prefix)
+
+// SignBLAKE2b computes the BLAKE2b-256 HMAC signature of body as expected by
+// BLAKE2bValidator
+func SignBLAKE2b(body []byte, secret string) string {
+	return hmacsig.Sign(body, secret, New256, Prefix)
+}
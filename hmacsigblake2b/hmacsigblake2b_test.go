@@ -0,0 +1,68 @@
+package hmacsigblake2b
+
+import (
+	"bytes"
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/donatj/hmacsig"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestBLAKE2bValidator(t *testing.T) {
+	body := []byte("This body is super")
+	sig := "blake2b=e04e4ea05ec159cca3012586ba05f4cee6bda5b17136d4434ed7b69014923e7c"
+
+	if s := SignBLAKE2b(body, "EvenDifferentKey"); s != sig {
+		t.Errorf("expected signature '%s'; got '%s'", sig, s)
+	}
+
+	tt := []struct {
+		sig    string
+		status int
+	}{
+		{sig, http.StatusOK},
+		{"BLAKE2B=" + sig[8:], http.StatusOK},
+		{SignBLAKE2b(body, "wrong"), http.StatusForbidden},
+		{"blake2b=zz", http.StatusBadRequest},
+		{hmacsig.SignSHA256(body, "EvenDifferentKey"), http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("X-Signature", tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		hmacsig.Handler(x, "EvenDifferentKey",
+			hmacsig.OptionHeader("X-Signature"),
+			hmacsig.OptionSignatureValidator(BLAKE2bValidator),
+			hmacsig.OptionSignatureFormat(Prefix, blake2b.Size256),
+		).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
+
+func TestHashPrefix(t *testing.T) {
+	if p := hmacsig.HashPrefix(crypto.BLAKE2b_256); p != Prefix {
+		t.Errorf("expected hmacsig.HashPrefix to match Prefix '%s'; got '%s'", Prefix, p)
+	}
+
+	v, err := hmacsig.ValidatorFor(crypto.BLAKE2b_256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("This body is super")
+	if sig := SignBLAKE2b(body, "EvenDifferentKey"); !v(body, sig, "EvenDifferentKey") {
+		t.Errorf("expected hmacsig.ValidatorFor to validate '%s'", sig)
+	}
+}
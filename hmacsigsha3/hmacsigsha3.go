@@ -0,0 +1,31 @@
+// Package hmacsigsha3 provides hmacsig validation of HMAC-SHA3-256
+// signatures. It is kept separate from hmacsig so that only users of SHA-3
+// build golang.org/x/crypto.
+package hmacsigsha3
+
+import (
+	"github.com/donatj/hmacsig"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// Prefix is the prefix of SHA3-256 signatures
+	Prefix = "sha3-256="
+
+	// SignatureHeader is the suggested header for SHA3-256 signatures,
+	// following GitHub's naming. No major provider has standardized one.
+	SignatureHeader = "X-Hub-Signature-Sha3-256"
+)
+
+// SHA3_256Validator implements the interface hmacsig.SignatureValidator and
+// SHA3-256 HMAC validation of signatures prefixed "sha3-256=".
+//
+// Use hmacsig.OptionSignatureFormat(Prefix, 32) alongside it to detect
+// malformed signatures.
+var SHA3_256Validator = hmacsig.NewValidator(sha3.New256, Prefix)
+
+// SignSHA3_256 computes the SHA3-256 HMAC signature of body as expected by
+// SHA3_256Validator
+func SignSHA3_256(body []byte, secret string) string {
+	return hmacsig.Sign(body, secret, sha3.New256, Prefix)
+}
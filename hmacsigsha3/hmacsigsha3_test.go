@@ -0,0 +1,67 @@
+package hmacsigsha3
+
+import (
+	"bytes"
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/donatj/hmacsig"
+)
+
+func TestSHA3_256Validator(t *testing.T) {
+	body := []byte("This body is super")
+	sig := "sha3-256=e4a661259bc529363619926a7e44ba96703f81e10ff8fc9ce73c99f089164c2a"
+
+	if s := SignSHA3_256(body, "EvenDifferentKey"); s != sig {
+		t.Errorf("expected signature '%s'; got '%s'", sig, s)
+	}
+
+	tt := []struct {
+		sig    string
+		status int
+	}{
+		{sig, http.StatusOK},
+		{"SHA3-256=" + sig[9:], http.StatusOK},
+		{SignSHA3_256(body, "wrong"), http.StatusForbidden},
+		{"sha3-256=zz", http.StatusBadRequest},
+		{hmacsig.SignSHA256(body, "EvenDifferentKey"), http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		hmacsig.Handler(x, "EvenDifferentKey",
+			hmacsig.OptionHeader(SignatureHeader),
+			hmacsig.OptionSignatureValidator(SHA3_256Validator),
+			hmacsig.OptionSignatureFormat(Prefix, 32),
+		).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
+
+func TestHashPrefix(t *testing.T) {
+	if p := hmacsig.HashPrefix(crypto.SHA3_256); p != Prefix {
+		t.Errorf("expected hmacsig.HashPrefix to match Prefix '%s'; got '%s'", Prefix, p)
+	}
+
+	v, err := hmacsig.ValidatorFor(crypto.SHA3_256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("This body is super")
+	if sig := SignSHA3_256(body, "EvenDifferentKey"); !v(body, sig, "EvenDifferentKey") {
+		t.Errorf("expected hmacsig.ValidatorFor to validate '%s'", sig)
+	}
+}
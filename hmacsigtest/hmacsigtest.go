@@ -0,0 +1,86 @@
+// Package hmacsigtest provides helpers for testing handlers protected by
+// hmacsig.
+package hmacsigtest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/donatj/hmacsig"
+)
+
+// Algorithm pairs the header a signature is sent in with the function that
+// computes it
+type Algorithm struct {
+	Header string
+	Sign   func(body []byte, secret string) string
+}
+
+var (
+	// SHA1 signs requests as expected by hmacsig.Handler
+	SHA1 = Algorithm{Header: hmacsig.GithubSignatureHeader, Sign: hmacsig.SignSHA1}
+
+	// SHA256 signs requests as expected by hmacsig.Handler256
+	SHA256 = Algorithm{Header: hmacsig.GithubSignatureHeader256, Sign: hmacsig.SignSHA256}
+
+	// SHA384 signs requests as expected by hmacsig.SHA384Validator in the
+	// hmacsig.SignatureHeader384 header
+	SHA384 = Algorithm{Header: hmacsig.SignatureHeader384, Sign: hmacsig.SignSHA384}
+
+	// SHA224 signs requests as expected by hmacsig.SHA224Validator in the
+	// hmacsig.SignatureHeader224 header
+	SHA224 = Algorithm{Header: hmacsig.SignatureHeader224, Sign: hmacsig.SignSHA224}
+)
+
+// NewRequest returns a new incoming server request, as httptest.NewRequest,
+// with body signed by secret using alg.
+func NewRequest(method, target string, body []byte, secret string, alg Algorithm) *http.Request {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	Sign(r, body, secret, alg)
+
+	return r
+}
+
+// Sign sets the signature header of r to the signature of body by secret
+// using alg. body must be the body r is sent with.
+func Sign(r *http.Request, body []byte, secret string, alg Algorithm) {
+	r.Header.Set(alg.Header, alg.Sign(body, secret))
+}
+
+// NewClientRequest returns a new outgoing client request, as http.NewRequest,
+// with body signed by secret using alg, for mock providers sending signed
+// requests to a receiver under test. GetBody is set, so the request may be
+// resent on redirect.
+func NewClientRequest(method, url string, body []byte, secret string, alg Algorithm) (*http.Request, error) {
+	r, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	Sign(r, body, secret, alg)
+
+	return r, nil
+}
+
+// SigningHandler returns an http.Handler which signs the responses of h by
+// secret using alg, without verifying requests, for mock upstreams whose
+// responses are verified by hmacsig.VerifyingTransport. The response is
+// buffered in full so the signature header can be set before it is written.
+func SigningHandler(h http.Handler, secret string, alg Algorithm) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		res := rec.Result()
+		body := rec.Body.Bytes()
+
+		for k, v := range res.Header {
+			w.Header()[k] = v
+		}
+		w.Header().Set(alg.Header, alg.Sign(body, secret))
+
+		w.WriteHeader(res.StatusCode)
+		w.Write(body)
+	})
+}
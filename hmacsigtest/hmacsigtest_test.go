@@ -0,0 +1,128 @@
+package hmacsigtest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/donatj/hmacsig"
+)
+
+func TestNewRequest(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	})
+
+	tt := []struct {
+		alg     Algorithm
+		handler http.Handler
+	}{
+		{SHA1, hmacsig.Handler(x, "supersecret")},
+		{SHA256, hmacsig.Handler256(x, "supersecret")},
+		{SHA384, hmacsig.Handler(x, "supersecret",
+			hmacsig.OptionHeader(hmacsig.SignatureHeader384),
+			hmacsig.OptionSignatureValidator(hmacsig.SHA384Validator))},
+		{SHA224, hmacsig.Handler(x, "supersecret",
+			hmacsig.OptionHeader(hmacsig.SignatureHeader224),
+			hmacsig.OptionSignatureValidator(hmacsig.SHA224Validator))},
+	}
+
+	for _, tc := range tt {
+		body := []byte("This is the body of the request")
+
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, NewRequest("POST", "/", body, "supersecret", tc.alg))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d for %s; got %d", http.StatusOK, tc.alg.Header, rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, NewRequest("POST", "/", body, "wrongsecret", tc.alg))
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d for %s with wrong secret; got %d", http.StatusForbidden, tc.alg.Header, rec.Code)
+		}
+	}
+}
+
+func TestNewClientRequest(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("success"))
+	})
+
+	srv := httptest.NewServer(hmacsig.Handler256(x, "supersecret"))
+	defer srv.Close()
+
+	tt := []struct {
+		secret string
+		status int
+	}{
+		{"supersecret", http.StatusOK},
+		{"wrongsecret", http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req, err := NewClientRequest("POST", srv.URL, []byte("This is the body of the request"), tc.secret, SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d with %s; got %d", tc.status, tc.secret, res.StatusCode)
+		}
+	}
+
+	if _, err := NewClientRequest("POST", "://bad", nil, "supersecret", SHA256); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
+
+func TestSigningHandler(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "mock")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("signed "))
+		w.Write([]byte("response"))
+	})
+
+	srv := httptest.NewServer(SigningHandler(x, "supersecret", SHA256))
+	defer srv.Close()
+
+	tt := []struct {
+		secret string
+		err    error
+	}{
+		{"supersecret", nil},
+		{"wrongsecret", hmacsig.ErrSignatureMismatch},
+	}
+
+	for _, tc := range tt {
+		client := &http.Client{Transport: &hmacsig.VerifyingTransport{Secret: tc.secret}}
+
+		res, err := client.Get(srv.URL)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("expected error '%v' with %s; got '%v'", tc.err, tc.secret, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		b, _ := io.ReadAll(res.Body)
+		if string(b) != "signed response" {
+			t.Errorf("expected body 'signed response'; got '%s'", b)
+		}
+
+		if res.StatusCode != http.StatusAccepted || res.Header.Get("X-Upstream") != "mock" {
+			t.Errorf("expected status and headers of the wrapped handler; got %d %v", res.StatusCode, res.Header)
+		}
+	}
+}
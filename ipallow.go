@@ -0,0 +1,213 @@
+package hmacsig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// MsgForbiddenIP is the message returned in the body when the client IP is
+// not in a range allowed by OptionAllowedCIDRs
+const MsgForbiddenIP = "Client IP not allowed"
+
+// ErrForbiddenIP is the failure reason when the client IP is not in a range
+// allowed by OptionAllowedCIDRs
+var ErrForbiddenIP = errors.New("hmacsig: client IP not allowed")
+
+// ErrInvalidCIDR is returned by NewHandler when a CIDR block passed to
+// OptionAllowedCIDRs cannot be parsed
+var ErrInvalidCIDR = fmt.Errorf("%w: invalid CIDR", ErrInvalidConfig)
+
+// OptionAllowedCIDRs configures the CIDR blocks, e.g. "192.30.252.0/22", the
+// client IP must be in, as a second factor alongside the signature. Requests
+// from any other IP are passed to the forbidden IP handler before the body is
+// read. The client IP is taken from RemoteAddr unless
+// OptionTrustForwardedFor is set.
+//
+// The blocks are parsed once, here. Blocks that cannot be parsed are reported
+// by NewHandler as ErrInvalidCIDR and otherwise match nothing. By default
+// requests from any IP are accepted.
+func OptionAllowedCIDRs(cidrs ...string) Option {
+	return func(mux *hmacSig) {
+		mux.allowedIPs = mux.parseCIDRs(cidrs)
+	}
+}
+
+// OptionTrustForwardedFor configures the client IP checked by
+// OptionAllowedCIDRs to be taken from the first address in the
+// X-Forwarded-For header when present.
+//
+// The header is set by the client, so this is only safe behind a proxy which
+// replaces it rather than appending to it. Behind proxies which append to it,
+// use OptionTrustedProxyHops or OptionTrustedProxies instead.
+func OptionTrustForwardedFor(mux *hmacSig) {
+	mux.trustForwardedFor = true
+}
+
+// OptionTrustedProxyHops configures the client IP checked by
+// OptionAllowedCIDRs to be taken from the X-Forwarded-For header as appended
+// to by the given number of trusted proxies in front of the server, each of
+// which appends the address it received the request from: the nth address
+// from the end of the header.
+//
+// For a single load balancer, n is 1 and the last address in the header is
+// used. If the chain is shorter than n, or the address is not an IP,
+// RemoteAddr is used. This takes precedence over OptionTrustForwardedFor.
+func OptionTrustedProxyHops(n int) Option {
+	return func(mux *hmacSig) {
+		mux.proxyHops = n
+	}
+}
+
+// OptionTrustedProxies configures the client IP checked by OptionAllowedCIDRs
+// to be the last address of the chain formed by the X-Forwarded-For header
+// followed by RemoteAddr that is not within the given CIDR blocks of trusted
+// proxies, walking back from RemoteAddr. Addresses before it, which the client
+// may have forged, are ignored.
+//
+// If RemoteAddr is not a trusted proxy the header is ignored. If an address
+// in the chain is not an IP, or every address is trusted, RemoteAddr is used.
+// Blocks that cannot be parsed are handled as by OptionAllowedCIDRs. This
+// takes precedence over OptionTrustedProxyHops and OptionTrustForwardedFor.
+func OptionTrustedProxies(cidrs ...string) Option {
+	return func(mux *hmacSig) {
+		mux.trustedProxies = mux.parseCIDRs(cidrs)
+	}
+}
+
+// parseCIDRs parses the given CIDR blocks, recording any that cannot be parsed
+// as the configuration error of xh
+func (xh *hmacSig) parseCIDRs(cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			xh.configErr = fmt.Errorf("%w: %q", ErrInvalidCIDR, cidr)
+			continue
+		}
+
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return prefixes
+}
+
+// OptionForbiddenIPHandler configures the http.Handler called when the client
+// IP is not in a range allowed by OptionAllowedCIDRs
+func OptionForbiddenIPHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.forbiddenIPHandler = handler
+	}
+}
+
+// DefaultForbiddenIPHandler is the default response to the client IP not
+// being in a range allowed by OptionAllowedCIDRs
+func DefaultForbiddenIPHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgForbiddenIP, http.StatusForbidden)
+}
+
+// JSONForbiddenIPHandler responds to the client IP not being in a range
+// allowed by OptionAllowedCIDRs with a JSON body of the form {"error":"..."}
+func JSONForbiddenIPHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgForbiddenIP, http.StatusForbidden)
+}
+
+// ipAllowed reports whether the client IP of r is in one of the allowed
+// ranges
+func (xh *hmacSig) ipAllowed(r *http.Request) bool {
+	if xh.allowedIPs == nil {
+		return true
+	}
+
+	ip, ok := xh.clientIP(r)
+	if !ok {
+		return false
+	}
+
+	return inPrefixes(ip, xh.allowedIPs)
+}
+
+// inPrefixes reports whether ip is within any of prefixes
+func inPrefixes(ip netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the IP of the client which sent r
+func (xh *hmacSig) clientIP(r *http.Request) (netip.Addr, bool) {
+	remote, ok := parseIP(r.RemoteAddr)
+
+	if xh.trustedProxies != nil {
+		if !ok || !inPrefixes(remote, xh.trustedProxies) {
+			return remote, ok
+		}
+
+		chain := forwardedFor(r)
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip, ok := parseIP(chain[i])
+			if !ok {
+				break
+			}
+
+			if !inPrefixes(ip, xh.trustedProxies) {
+				return ip, true
+			}
+		}
+
+		return remote, true
+	}
+
+	if xh.proxyHops > 0 {
+		chain := forwardedFor(r)
+		if i := len(chain) - xh.proxyHops; i >= 0 {
+			if ip, ok := parseIP(chain[i]); ok {
+				return ip, true
+			}
+		}
+
+		return remote, ok
+	}
+
+	if xh.trustForwardedFor {
+		first, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+		if ip, ok := parseIP(first); ok {
+			return ip, true
+		}
+	}
+
+	return remote, ok
+}
+
+// forwardedFor returns the addresses of every X-Forwarded-For header of r in
+// order
+func forwardedFor(r *http.Request) []string {
+	var chain []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		chain = append(chain, strings.Split(v, ",")...)
+	}
+
+	return chain
+}
+
+// parseIP parses s as an IP address with or without a port, unmapping IPv4
+// addresses mapped to IPv6
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return ip.Unmap(), true
+}
@@ -0,0 +1,125 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedCIDRs(t *testing.T) {
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	allow := OptionAllowedCIDRs("192.30.252.0/22", "2001:db8::/32")
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		options    []Option
+		status     int
+	}{
+		{"no allowlist", "203.0.113.7:1234", "", nil, http.StatusOK},
+		{"allowed", "192.30.252.41:1234", "", []Option{allow}, http.StatusOK},
+		{"allowed ipv6", "[2001:db8::1]:1234", "", []Option{allow}, http.StatusOK},
+		{"allowed mapped ipv4", "[::ffff:192.30.252.41]:1234", "", []Option{allow}, http.StatusOK},
+		{"allowed without port", "192.30.252.41", "", []Option{allow}, http.StatusOK},
+		{"forbidden", "203.0.113.7:1234", "", []Option{allow}, http.StatusForbidden},
+		{"forbidden, handler", "203.0.113.7:1234", "", []Option{allow, OptionForbiddenIPHandler(teapot)}, http.StatusTeapot},
+		{"unparsable remote", "somewhere", "", []Option{allow}, http.StatusForbidden},
+		{"forwarded untrusted", "203.0.113.7:1234", "192.30.252.41", []Option{allow}, http.StatusForbidden},
+		{"forwarded trusted", "203.0.113.7:1234", "192.30.252.41, 10.0.0.1", []Option{allow, OptionTrustForwardedFor}, http.StatusOK},
+		{"forwarded trusted, forbidden", "192.30.252.41:1234", "203.0.113.7", []Option{allow, OptionTrustForwardedFor}, http.StatusForbidden},
+		{"forwarded trusted, garbage", "192.30.252.41:1234", "garbage", []Option{allow, OptionTrustForwardedFor}, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+	}
+}
+
+func TestAllowedCIDRsInvalid(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	_, err := NewHandler(x, "supersecret", OptionAllowedCIDRs("192.30.252.0/22", "not a cidr"))
+	if !errors.Is(err, ErrInvalidCIDR) || !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidCIDR; got %v", err)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	hops := OptionTrustedProxyHops(2)
+	proxies := OptionTrustedProxies("10.0.0.0/8", "172.16.0.0/12")
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		options    []Option
+		client     string
+	}{
+		{"hops, two proxies", "10.0.0.2:1234", []string{"192.30.252.41, 10.0.0.1"}, []Option{hops}, "192.30.252.41"},
+		{"hops, spoofed prefix", "10.0.0.2:1234", []string{"203.0.113.7, 192.30.252.41, 10.0.0.1"}, []Option{hops}, "192.30.252.41"},
+		{"hops, split headers", "10.0.0.2:1234", []string{"203.0.113.7", "192.30.252.41, 10.0.0.1"}, []Option{hops}, "192.30.252.41"},
+		{"hops, chain too short", "10.0.0.2:1234", []string{"10.0.0.1"}, []Option{hops}, "10.0.0.2"},
+		{"hops, no header", "10.0.0.2:1234", nil, []Option{hops}, "10.0.0.2"},
+		{"hops, garbage", "10.0.0.2:1234", []string{"garbage, 10.0.0.1"}, []Option{hops}, "10.0.0.2"},
+		{"hops, single proxy", "10.0.0.1:1234", []string{"203.0.113.7, 192.30.252.41"}, []Option{OptionTrustedProxyHops(1)}, "192.30.252.41"},
+		{"proxies, multi-hop", "10.0.0.2:1234", []string{"192.30.252.41, 172.16.0.5, 10.0.0.1"}, []Option{proxies}, "192.30.252.41"},
+		{"proxies, spoofed prefix", "10.0.0.2:1234", []string{"192.30.252.41, 203.0.113.7, 10.0.0.1"}, []Option{proxies}, "203.0.113.7"},
+		{"proxies, untrusted remote", "203.0.113.7:1234", []string{"192.30.252.41"}, []Option{proxies}, "203.0.113.7"},
+		{"proxies, all trusted", "10.0.0.2:1234", []string{"10.0.0.3, 10.0.0.1"}, []Option{proxies}, "10.0.0.2"},
+		{"proxies, garbage", "10.0.0.2:1234", []string{"192.30.252.41, garbage, 10.0.0.1"}, []Option{proxies}, "10.0.0.2"},
+		{"proxies override hops", "10.0.0.2:1234", []string{"192.30.252.41, 203.0.113.7, 10.0.0.1"}, []Option{hops, proxies}, "203.0.113.7"},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", nil)
+		req.RemoteAddr = tc.remoteAddr
+		for _, v := range tc.forwarded {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+
+		xh := newHMACSig(nil, "supersecret", tc.options...)
+		ip, ok := xh.clientIP(req)
+		if !ok || ip.String() != tc.client {
+			t.Errorf("%s: expected client %s; got %v", tc.name, tc.client, ip)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+	req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+	req.Header.Set("X-Forwarded-For", "192.30.252.41, 203.0.113.7, 10.0.0.1")
+	req.RemoteAddr = "10.0.0.2:1234"
+	rec := httptest.NewRecorder()
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	Handler(x, "supersecret", OptionAllowedCIDRs("192.30.252.0/22"), proxies).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected spoofed client to be forbidden; got %d", rec.Code)
+	}
+
+	if _, err := NewHandler(x, "supersecret", OptionTrustedProxies("10.0.0.0/33")); !errors.Is(err, ErrInvalidCIDR) {
+		t.Errorf("expected ErrInvalidCIDR; got %v", err)
+	}
+}
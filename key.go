@@ -0,0 +1,65 @@
+package hmacsig
+
+import (
+	"hash"
+	"net/http"
+)
+
+// KeyValidator validates the body of a request against the requests
+// signature and servers secret key, supplied as raw bytes. It is the
+// counterpart of SignatureValidator for binary keys, such as 32 random bytes,
+// that are not text.
+type KeyValidator func(body []byte, sig string, key []byte) bool
+
+// NewKeyValidator returns a KeyValidator for signatures consisting of prefix,
+// e.g. "sha256=", followed by the hex encoded HMAC computed with h. Signatures
+// for it may be created with SignKey.
+func NewKeyValidator(h func() hash.Hash, prefix string) KeyValidator {
+	m := newHMACHash(h)
+
+	return func(body []byte, sig string, key []byte) bool {
+		return validateKey(body, sig, key, m, prefix, EncodingHex) == nil
+	}
+}
+
+// errorFunc adapts the KeyValidator to a validatorFunc, reporting
+// ErrSignatureMismatch when it returns false
+func (v KeyValidator) errorFunc() validatorFunc {
+	if v == nil {
+		return nil
+	}
+
+	return func(r *http.Request, body []byte, sig, secret string) error {
+		if !v(body, sig, []byte(secret)) {
+			return ErrSignatureMismatch
+		}
+
+		return nil
+	}
+}
+
+// OptionKeyValidator configures the KeyValidator validated against, in place
+// of the SignatureValidator. As with OptionSignatureValidator, this clears
+// the signature format.
+func OptionKeyValidator(validator KeyValidator) Option {
+	return func(mux *hmacSig) {
+		mux.validator = validator.errorFunc()
+		mux.format = nil
+		mux.hash = nil
+	}
+}
+
+// OptionSecretBytes configures the set of secret keys, supplied as raw bytes,
+// a signature is accepted for, replacing the secret passed to Handler as
+// OptionSecrets does. Keys need not be valid UTF-8. The handler holds them as
+// strings, which Go allows to contain arbitrary bytes, so every validator
+// receives them byte for byte and a KeyValidator receives the original bytes.
+func OptionSecretBytes(keys ...[]byte) Option {
+	return func(mux *hmacSig) {
+		mux.secrets = make([]string, len(keys))
+		mux.secretNames = nil
+		for i, key := range keys {
+			mux.secrets[i] = string(key)
+		}
+	}
+}
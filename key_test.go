@@ -0,0 +1,59 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSecretBytes(t *testing.T) {
+	body := []byte("This body is super")
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(0xe0 + i)
+	}
+	sig := "sha256=a5d1a5e48130ffee3d4a09544d3b78f9c757026d2c15d20a3a213235fa458862"
+
+	if utf8.Valid(key) {
+		t.Fatal("expected key not to be valid UTF-8")
+	}
+
+	if s := SignKey(body, key, sha256.New, "sha256="); s != sig {
+		t.Errorf("expected signature '%s'; got '%s'", sig, s)
+	}
+
+	v := NewKeyValidator(sha256.New, "sha256=")
+	if !v(body, sig, key) {
+		t.Error("expected KeyValidator to validate")
+	}
+	if v(body, sig, key[1:]) {
+		t.Error("expected KeyValidator not to validate with the wrong key")
+	}
+
+	tt := []struct {
+		name    string
+		options []Option
+	}{
+		{"secret bytes", []Option{OptionSecretBytes(key)}},
+		{"secret bytes, key validator", []Option{OptionSecretBytes([]byte("old"), key), OptionKeyValidator(v)}},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler256(x, "", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status OK; got %d", tc.name, rec.Code)
+		}
+	}
+}
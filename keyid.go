@@ -0,0 +1,90 @@
+package hmacsig
+
+import (
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ErrUnknownKeyID is the failure reason when a signature of the form
+// "keyid=<id>,sig=<hex>" named a key ID for which no secret is configured. It
+// wraps ErrSignatureMismatch.
+var ErrUnknownKeyID = fmt.Errorf("%w: unknown key id", ErrSignatureMismatch)
+
+// NewKeyIDValidator returns a SignatureValidatorE for signatures of the form
+// "keyid=<id>,sig=<hex>", where the hex encoded HMAC computed with h is
+// validated against the secret returned by lookup for the key ID. The secret
+// configured on the handler is ignored.
+//
+// Signatures lacking either field are reported as ErrMalformedSignature, and
+// key IDs for which lookup returns false or an empty secret as
+// ErrUnknownKeyID. Signatures for
+// the returned validator may be created with SignKeyID.
+func NewKeyIDValidator(h func() hash.Hash, lookup func(keyID string) (secret string, ok bool)) SignatureValidatorE {
+	m := newHMACHash(h)
+
+	return func(body []byte, sig, _ string) error {
+		keyID, digest, ok := parseKeyID(sig)
+		if !ok {
+			return ErrMalformedSignature
+		}
+
+		secret, ok := lookup(keyID)
+		if !ok || secret == "" {
+			return ErrUnknownKeyID
+		}
+
+		return validateE(body, digest, secret, m, "")
+	}
+}
+
+// OptionKeyIDFunc configures validation of signatures of the form
+// "keyid=<id>,sig=<hex>" with the secret for each key ID returned by lookup,
+// see NewKeyIDValidator. The secret passed to Handler is ignored and may be
+// empty.
+//
+// As with OptionSignatureValidatorE, this clears the signature format.
+func OptionKeyIDFunc(h func() hash.Hash, lookup func(keyID string) (secret string, ok bool)) Option {
+	return func(mux *hmacSig) {
+		OptionSignatureValidatorE(NewKeyIDValidator(h, lookup))(mux)
+		mux.keyIDSecrets = true
+	}
+}
+
+// OptionKeyIDSecrets is OptionKeyIDFunc looking key IDs up in secrets, which
+// maps each key ID to its secret. The map is copied.
+func OptionKeyIDSecrets(h func() hash.Hash, secrets map[string]string) Option {
+	m := make(map[string]string, len(secrets))
+	for id, secret := range secrets {
+		m[id] = secret
+	}
+
+	return OptionKeyIDFunc(h, func(keyID string) (string, bool) {
+		secret, ok := m[keyID]
+		return secret, ok
+	})
+}
+
+// SignKeyID computes the HMAC of body with the given secret and hash,
+// returning it hex encoded in the form "keyid=<id>,sig=<hex>" as expected by
+// NewKeyIDValidator
+func SignKeyID(body []byte, keyID, secret string, h func() hash.Hash) string {
+	return "keyid=" + keyID + "," + Sign(body, secret, h, "sig=")
+}
+
+// parseKeyID splits a signature of the form "keyid=<id>,sig=<hex>", in either
+// order, into its key ID and digest
+func parseKeyID(sig string) (keyID, digest string, ok bool) {
+	var hasKeyID, hasSig bool
+	for _, part := range strings.Split(sig, ",") {
+		k, v, _ := strings.Cut(strings.Trim(part, asciiSpace), "=")
+		switch k {
+		case "keyid":
+			keyID, hasKeyID = v, true
+		case "sig":
+			digest, hasSig = v, true
+		}
+	}
+
+	return keyID, digest, hasKeyID && hasSig && keyID != "" && digest != ""
+}
@@ -0,0 +1,102 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyIDValidator(t *testing.T) {
+	body := []byte("This is the body of the request")
+	secrets := map[string]string{"abc": "supersecret", "def": "othersecret", "empty": ""}
+
+	tt := []struct {
+		sig string
+		err error
+	}{
+		{SignKeyID(body, "abc", "supersecret", sha256.New), nil},
+		{SignKeyID(body, "def", "othersecret", sha256.New), nil},
+		{"sig=" + SignKeyID(body, "abc", "supersecret", sha256.New)[len("keyid=abc,sig="):] + ", keyid=abc", nil},
+		{SignKeyID(body, "abc", "othersecret", sha256.New), ErrSignatureMismatch},
+		{SignKeyID(body, "xyz", "supersecret", sha256.New), ErrUnknownKeyID},
+		{SignKeyID(body, "empty", "", sha256.New), ErrUnknownKeyID},
+		{"keyid=abc", ErrMalformedSignature},
+		{"sig=00", ErrMalformedSignature},
+		{"keyid=abc,sig=zz", ErrSignatureEncoding},
+		{"keyid=abc,sig=00", ErrSignatureLength},
+	}
+
+	v := NewKeyIDValidator(sha256.New, func(keyID string) (string, bool) {
+		secret, ok := secrets[keyID]
+		return secret, ok
+	})
+
+	for _, tc := range tt {
+		err := v(body, tc.sig, "ignored")
+		if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+			t.Errorf("expected '%v' for '%s'; got '%v'", tc.err, tc.sig, err)
+		}
+
+		if tc.err == ErrSignatureMismatch && errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("expected mismatch distinct from unknown key id for '%s'", tc.sig)
+		}
+	}
+}
+
+func TestKeyIDSecrets(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		sig    string
+		status int
+		err    error
+	}{
+		{SignKeyID(body, "abc", "supersecret", sha256.New), http.StatusOK, nil},
+		{SignKeyID(body, "abc", "wrongsecret", sha256.New), http.StatusForbidden, ErrSignatureMismatch},
+		{SignKeyID(body, "xyz", "supersecret", sha256.New), http.StatusForbidden, ErrUnknownKeyID},
+		{SignSHA256(body, "supersecret"), http.StatusBadRequest, ErrMalformedSignature},
+	}
+
+	secrets := map[string]string{"abc": "supersecret"}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("X-Signature", tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		var got error
+		failed := func(status int) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ErrorFromContext(r.Context())
+				w.WriteHeader(status)
+			})
+		}
+
+		h, err := NewHandler(x, "",
+			OptionHeader("X-Signature"),
+			OptionKeyIDSecrets(sha256.New, secrets),
+			OptionVerifyFailedHandler(failed(http.StatusForbidden)),
+			OptionMalformedSignatureHandler(failed(http.StatusBadRequest)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.sig, rec.Code)
+		}
+
+		if !errors.Is(got, tc.err) {
+			t.Errorf("expected reason '%v'; got '%v'", tc.err, got)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package hmacsig
+
+import (
+	"encoding/hex"
+	"hash"
+)
+
+// NewLabeledValidator returns a SignatureValidator for signatures consisting
+// of prefix, e.g. "sha256=", followed by the hex encoded HMAC computed with h
+// of label followed by the body, i.e. HMAC(secret, label || body). The label
+// is a fixed context string domain separating the signatures of one scheme
+// from another; it is written once, before the body, with no separator.
+//
+// With an empty label it is equivalent to NewValidator. Signatures for the
+// returned SignatureValidator may be created with SignLabeled.
+func NewLabeledValidator(h func() hash.Hash, prefix string, label []byte) SignatureValidator {
+	m := newHMACHash(h)
+	label = append([]byte(nil), label...)
+
+	return func(body []byte, sig, secret string) bool {
+		sigDigest, err := decodeSignature(sig, prefix, EncodingHex)
+		if err != nil {
+			return false
+		}
+
+		ok, _ := m.equal(label, body, []byte(secret), sigDigest)
+		return ok
+	}
+}
+
+// SignLabeled computes the HMAC of label followed by body with the given
+// secret and hash, returning the hex encoded digest preceded by prefix, as
+// expected by NewLabeledValidator
+func SignLabeled(body []byte, secret string, h func() hash.Hash, prefix string, label []byte) string {
+	return prefix + hex.EncodeToString(labeledDigest(label, body, []byte(secret), h))
+}
@@ -0,0 +1,45 @@
+package hmacsig
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestLabeledValidator(t *testing.T) {
+	body := []byte("This body is super")
+	label := []byte("partner-v1:")
+	sig := "sha256=6a2ab8060e111af10a98f42a791afe33233182a575caa34e9ea529f9fba11b46"
+
+	if s := SignLabeled(body, "EvenDifferentKey", sha256.New, "sha256=", label); s != sig {
+		t.Errorf("expected signature '%s'; got '%s'", sig, s)
+	}
+
+	v := NewLabeledValidator(sha256.New, "sha256=", label)
+
+	tt := []struct {
+		sig   string
+		body  []byte
+		valid bool
+	}{
+		{sig, body, true},
+		{sig, append(append([]byte(nil), label...), body...), false},
+		{SignSHA256(body, "EvenDifferentKey"), body, false},
+		{SignLabeled(body, "wrong", sha256.New, "sha256=", label), body, false},
+		{"sha256=zz", body, false},
+	}
+
+	for _, tc := range tt {
+		if valid := v(tc.body, tc.sig, "EvenDifferentKey"); valid != tc.valid {
+			t.Errorf("expected %v for '%s' over '%s'; got %v", tc.valid, tc.sig, tc.body, valid)
+		}
+	}
+
+	unlabeled := NewLabeledValidator(sha256.New, "sha256=", nil)
+	if !unlabeled(body, SignSHA256(body, "EvenDifferentKey"), "EvenDifferentKey") {
+		t.Error("expected an empty label to validate as NewValidator")
+	}
+
+	if s := SignLabeled(body, "EvenDifferentKey", sha256.New, "sha256=", []byte{}); s != SignSHA256(body, "EvenDifferentKey") {
+		t.Errorf("expected an empty label to sign as Sign; got '%s'", s)
+	}
+}
@@ -0,0 +1,40 @@
+package hmacsig
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+)
+
+var (
+	sha1HMAC   = newHMACHash(sha1.New)
+	sha224HMAC = newHMACHash(sha256.New224)
+	sha256HMAC = newHMACHash(sha256.New)
+	sha384HMAC = newHMACHash(sha512.New384)
+)
+
+// hmacHash computes HMACs with a hash function through crypto/hmac, as
+// labeledDigest does. Each computation keys an instance of its own, so no key
+// outlives the computation it is used for.
+type hmacHash struct {
+	h func() hash.Hash
+}
+
+func newHMACHash(h func() hash.Hash) *hmacHash {
+	return &hmacHash{h: h}
+}
+
+// digest computes the raw HMAC of body with the given key
+func (m *hmacHash) digest(body, key []byte) []byte {
+	return labeledDigest(nil, body, key, m.h)
+}
+
+// equal reports whether the raw HMAC of label followed by body with the
+// given key equals the decoded signature sig, in constant time as
+// equalDigest does, and the size of the HMAC
+func (m *hmacHash) equal(label, body, key, sig []byte) (ok bool, size int) {
+	d := labeledDigest(label, body, key, m.h)
+
+	return equalDigest(d, sig), len(d)
+}
@@ -0,0 +1,92 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestHMACHashConcurrent(t *testing.T) {
+	tt := []struct {
+		body   string
+		secret string
+		sig    string
+	}{
+		{"This body is super", "EvenDifferentKey", "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"},
+		{"This body is super", "ThisKeyIsAGreatSecretYouShouldNotUseIt", "sha256=c95befe0113be8f0b8a9bd57d2b13f09d271a770788b22109bbbf033866f8bc0"},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				for k, tc := range tt {
+					if !SHA256Validator([]byte(tc.body), tc.sig, tc.secret) {
+						t.Errorf("expected valid signature for case %d", k)
+					}
+
+					if SHA256Validator([]byte(tc.body), tc.sig, tc.secret+"x") {
+						t.Errorf("expected invalid signature for case %d with wrong secret", k)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHMACHashDigest(t *testing.T) {
+	body := []byte("This body is super")
+	label := []byte("label")
+	hashes := []func() hash.Hash{sha1.New, sha256.New, sha512.New, sha3.New256}
+
+	for _, h := range hashes {
+		m := newHMACHash(h)
+		size := h().BlockSize()
+
+		for _, n := range []int{0, 1, size - 1, size, size + 1, size * 3} {
+			secret := strings.Repeat("k", n)
+
+			expected := hmacSum(h, secret, label, body)
+
+			if d := m.digest(body, []byte(secret)); !bytes.Equal(d, hmacSum(h, secret, body)) {
+				t.Errorf("expected digest to match crypto/hmac for a %d byte key", n)
+			}
+
+			if ok, size := m.equal(label, body, []byte(secret), expected); !ok || size != len(expected) {
+				t.Errorf("expected equal to report %d byte match for a %d byte key; got %v %d", len(expected), n, ok, size)
+			}
+		}
+	}
+}
+
+// hmacSum computes the HMAC of parts with crypto/hmac directly
+func hmacSum(h func() hash.Hash, key string, parts ...[]byte) []byte {
+	mac := hmac.New(h, []byte(key))
+	for _, p := range parts {
+		mac.Write(p)
+	}
+
+	return mac.Sum(nil)
+}
+
+func BenchmarkSHA256Validator(b *testing.B) {
+	body := []byte("This body is super")
+	sig := SignSHA256(body, "EvenDifferentKey")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SHA256Validator(body, sig, "EvenDifferentKey")
+	}
+}
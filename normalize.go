@@ -0,0 +1,62 @@
+package hmacsig
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+// OptionBodyNormalizer configures a function applied to the body before its
+// signature is computed, for senders which canonicalize the body before
+// signing it, for instance rewriting its line endings. The normalized body is
+// what the wrapped handler receives, with Content-Length updated to match.
+// The limit configured by OptionMaxBodyBytes applies to the body as received.
+//
+// Normalization changes what is authenticated: every body normalizing to the
+// same bytes carries the same signature, so a signature for one validates any
+// of them. The function must match the sender's canonicalization exactly, and
+// should be no looser than is needed to accept its bodies. NormalizeLF and
+// NormalizeCRLF cover line endings.
+//
+// The body is buffered in full, so OptionSpillToDisk, OptionReuseGetBody and
+// OptionVerifyOnRead do not apply. By default the body is verified as it is.
+func OptionBodyNormalizer(fn func(body []byte) []byte) Option {
+	return func(mux *hmacSig) {
+		mux.bodyNormalizer = fn
+	}
+}
+
+// NormalizeLF is a body normalizer for OptionBodyNormalizer replacing CRLF
+// line endings with LF
+func NormalizeLF(body []byte) []byte {
+	return bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))
+}
+
+// NormalizeCRLF is a body normalizer for OptionBodyNormalizer replacing LF
+// line endings with CRLF. Existing CRLF line endings are left as they are.
+func NormalizeCRLF(body []byte) []byte {
+	return bytes.ReplaceAll(NormalizeLF(body), []byte("\n"), []byte("\r\n"))
+}
+
+// normalizeBody returns the body b normalized by the function configured by
+// OptionBodyNormalizer and a shallow copy of r whose Content-Length matches
+// it, or r and b if none is configured
+func (xh *hmacSig) normalizeBody(r *http.Request, b []byte) (*http.Request, []byte) {
+	if xh.bodyNormalizer == nil {
+		return r, b
+	}
+
+	nb := xh.bodyNormalizer(b)
+	if len(nb) == len(b) {
+		return r, nb
+	}
+
+	r = r.WithContext(r.Context())
+	r.Header = r.Header.Clone()
+	if r.Header.Get("Content-Length") != "" {
+		r.Header.Set("Content-Length", strconv.Itoa(len(nb)))
+	}
+	r.ContentLength = int64(len(nb))
+
+	return r, nb
+}
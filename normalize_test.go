@@ -0,0 +1,68 @@
+package hmacsig
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestBodyNormalizer(t *testing.T) {
+	lf := []byte("line one\nline two\n")
+	crlf := []byte("line one\r\nline two\r\n")
+
+	tt := []struct {
+		body       []byte
+		signed     []byte
+		normalizer func([]byte) []byte
+		options    []Option
+		status     int
+		received   []byte
+	}{
+		{crlf, lf, nil, nil, http.StatusForbidden, nil},
+		{crlf, lf, NormalizeLF, nil, http.StatusOK, lf},
+		{lf, lf, NormalizeLF, nil, http.StatusOK, lf},
+		{lf, crlf, NormalizeCRLF, nil, http.StatusOK, crlf},
+		{crlf, crlf, NormalizeCRLF, nil, http.StatusOK, crlf},
+		{crlf, crlf, NormalizeLF, nil, http.StatusForbidden, nil},
+		{crlf, lf, NormalizeLF, []Option{OptionSpillToDisk(4, "")}, http.StatusOK, lf},
+		{crlf, lf, NormalizeLF, []Option{OptionVerifyOnRead}, http.StatusOK, lf},
+		{crlf, lf, NormalizeLF, []Option{OptionReuseGetBody}, http.StatusOK, lf},
+		{crlf, lf, NormalizeLF, []Option{OptionMaxBodyBytes(int64(len(lf)))}, http.StatusRequestEntityTooLarge, nil},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(tc.body))
+		req.Header.Set("Content-Length", strconv.Itoa(len(tc.body)))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(tc.signed, "supersecret"))
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if !bytes.Equal(b, tc.received) {
+				t.Errorf("expected body %q; got %q", tc.received, b)
+			}
+
+			if r.ContentLength != int64(len(tc.received)) {
+				t.Errorf("expected content length %d; got %d", len(tc.received), r.ContentLength)
+			}
+
+			if cl := r.Header.Get("Content-Length"); cl != strconv.Itoa(len(tc.received)) {
+				t.Errorf("expected Content-Length header %d; got %s", len(tc.received), cl)
+			}
+		})
+
+		options := tc.options
+		if tc.normalizer != nil {
+			options = append(options, OptionBodyNormalizer(tc.normalizer))
+		}
+
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %q; got %d", tc.status, tc.body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package hmacsig
+
+import (
+	"hash"
+	"strings"
+)
+
+// NewPrefixesValidator returns a SignatureValidator for signatures consisting
+// of any of prefixes, e.g. "sha256=" or "hmac-sha256=", followed by the hex
+// encoded HMAC computed with h. Whichever prefix the signature carries is
+// stripped before the digest is compared; signatures carrying none of them
+// are rejected. It suits migrations where senders change prefix for the same
+// algorithm.
+//
+// The prefixes are matched case-insensitively, the longest matching prefix
+// taking precedence, so that "" may be included to accept a bare digest.
+func NewPrefixesValidator(h func() hash.Hash, prefixes ...string) SignatureValidator {
+	v := NewPrefixesValidatorE(h, prefixes...)
+
+	return func(body []byte, sig, secret string) bool {
+		return v(body, sig, secret) == nil
+	}
+}
+
+// NewPrefixesValidatorE is the SignatureValidatorE counterpart of
+// NewPrefixesValidator. Signatures carrying none of the prefixes are
+// reported as ErrSignaturePrefix.
+func NewPrefixesValidatorE(h func() hash.Hash, prefixes ...string) SignatureValidatorE {
+	m := newHMACHash(h)
+	prefixes = append([]string(nil), prefixes...)
+
+	return func(body []byte, sig, secret string) error {
+		return validatePrefixes(body, sig, secret, m, prefixes)
+	}
+}
+
+// sha1LegacyPrefixes are the prefixes accepted by SHA1LegacyValidator
+var sha1LegacyPrefixes = []string{"sha1=", ""}
+
+// SHA1LegacyValidator implements the interface SignatureValidator and SHA-1
+// HMAC validation of signatures prefixed "sha1=" or bare, as sent in
+// X-Hub-Signature by legacy senders predating the prefix. SHA1Validator,
+// which requires the prefix, remains the default.
+//
+// With Handler, OptionSignaturePrefixes("sha1=", "") configures the same
+// while keeping detection of malformed signatures.
+func SHA1LegacyValidator(body []byte, sig, secret string) bool {
+	return validatePrefixes(body, sig, secret, sha1HMAC, sha1LegacyPrefixes) == nil
+}
+
+// validatePrefixes is validateE for whichever of prefixes sig carries
+func validatePrefixes(body []byte, sig, secret string, m *hmacHash, prefixes []string) error {
+	prefix, ok := matchPrefix(sig, prefixes)
+	if !ok {
+		return ErrSignaturePrefix
+	}
+
+	return validateE(body, sig, secret, m, prefix)
+}
+
+// OptionSignaturePrefixes configures the prefixes accepted before the hex
+// encoded digest, as OptionSignaturePrefix does for one, e.g. both "sha256="
+// and "hmac-sha256=" while senders migrate from one to the other. Signatures
+// with any other prefix are rejected, and the first prefix is reported as the
+// Prefix of the Verification.
+//
+// Like OptionSignaturePrefix it applies to the algorithm configured by
+// Handler, Handler256 or OptionDefaultsSHA256, must follow them, and has no
+// effect following OptionSignatureValidator or without any prefixes.
+func OptionSignaturePrefixes(prefixes ...string) Option {
+	return func(mux *hmacSig) {
+		if mux.hash == nil || len(prefixes) == 0 {
+			return
+		}
+
+		prefixes = append([]string(nil), prefixes...)
+		mux.validator = NewPrefixesValidatorE(mux.hash, prefixes...).errorFunc()
+		mux.format = &signatureFormat{prefix: prefixes[0], size: mux.hash().Size(), prefixes: prefixes}
+	}
+}
+
+// matchPrefix returns the longest of prefixes sig carries, after surrounding
+// whitespace, matched case-insensitively, or false if it carries none
+func matchPrefix(sig string, prefixes []string) (string, bool) {
+	sig = strings.TrimLeft(sig, asciiSpace)
+
+	match, ok := "", false
+	for _, prefix := range prefixes {
+		if len(prefix) > len(sig) || !strings.EqualFold(sig[:len(prefix)], prefix) {
+			continue
+		}
+
+		if !ok || len(prefix) > len(match) {
+			match, ok = prefix, true
+		}
+	}
+
+	return match, ok
+}
@@ -0,0 +1,127 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrefixesValidator(t *testing.T) {
+	body := []byte("This body is super")
+	digest := Sign(body, "EvenDifferentKey", sha256.New, "")
+
+	tt := []struct {
+		prefixes []string
+		sig      string
+		err      error
+	}{
+		{[]string{"sha256=", "hmac-sha256="}, "sha256=" + digest, nil},
+		{[]string{"sha256=", "hmac-sha256="}, "hmac-sha256=" + digest, nil},
+		{[]string{"sha256=", "hmac-sha256="}, "HMAC-SHA256=" + digest, nil},
+		{[]string{"sha256=", "hmac-sha256="}, " sha256=" + digest + "\n", nil},
+		{[]string{"sha256=", "hmac-sha256="}, "sha1=" + digest, ErrSignaturePrefix},
+		{[]string{"sha256=", "hmac-sha256="}, digest, ErrSignaturePrefix},
+		{[]string{"sha256=", "hmac-sha256="}, "hmac-sha256=" + Sign(body, "WrongKey", sha256.New, ""), ErrSignatureMismatch},
+		{[]string{"sha256=", "hmac-sha256="}, "hmac-sha256=zz", ErrSignatureEncoding},
+		{[]string{"", "sha256="}, digest, nil},
+		{[]string{"", "sha256="}, "sha256=" + digest, nil},
+		{[]string{"sha256="}, "", ErrSignaturePrefix},
+		{nil, "sha256=" + digest, ErrSignaturePrefix},
+	}
+
+	for _, tc := range tt {
+		err := NewPrefixesValidatorE(sha256.New, tc.prefixes...)(body, tc.sig, "EvenDifferentKey")
+		if !errors.Is(err, tc.err) || (err == nil) != (tc.err == nil) {
+			t.Errorf("expected '%v' for %q with %q; got '%v'", tc.err, tc.sig, tc.prefixes, err)
+		}
+
+		if ok := NewPrefixesValidator(sha256.New, tc.prefixes...)(body, tc.sig, "EvenDifferentKey"); ok != (tc.err == nil) {
+			t.Errorf("expected %v for %q with %q; got %v", tc.err == nil, tc.sig, tc.prefixes, ok)
+		}
+	}
+}
+
+func TestSignaturePrefixes(t *testing.T) {
+	body := []byte("This body is super")
+	digest := Sign(body, "EvenDifferentKey", sha256.New, "")
+
+	tt := []struct {
+		sig     string
+		options []Option
+		status  int
+	}{
+		{"sha256=" + digest, nil, http.StatusOK},
+		{"hmac-sha256=" + digest, nil, http.StatusOK},
+		{"sha256:" + digest, nil, http.StatusBadRequest},
+		{"hmac-sha256=" + digest[:10], nil, http.StatusBadRequest},
+		{"hmac-sha256=" + Sign(body, "WrongKey", sha256.New, ""), nil, http.StatusForbidden},
+		{"hmac-sha256=" + digest, []Option{OptionSpillToDisk(4, "")}, http.StatusOK},
+		{"sha256:" + digest, []Option{OptionSpillToDisk(4, "")}, http.StatusBadRequest},
+		{"hmac-sha256=" + digest, []Option{OptionVerifyOnRead}, http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v, ok := FromContext(r.Context()); ok && v.Prefix != "sha256=" {
+				t.Errorf("expected verification prefix %q; got %q", "sha256=", v.Prefix)
+			}
+		})
+
+		options := append([]Option{OptionSignaturePrefixes("sha256=", "hmac-sha256=")}, tc.options...)
+		Handler256(x, "EvenDifferentKey", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %q; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
+
+func TestSHA1Legacy(t *testing.T) {
+	body := []byte("This is the body of the request")
+	digest := "0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+
+	tt := []struct {
+		sig    string
+		valid  bool
+		status int
+	}{
+		{"sha1=" + digest, true, http.StatusOK},
+		{digest, true, http.StatusOK},
+		{" " + digest + "\n", true, http.StatusOK},
+		{"SHA1=" + digest, true, http.StatusOK},
+		{"sha256=" + digest, false, http.StatusBadRequest},
+		{"587eed5390987ba9ee890cafa946eed9dacf2e52", false, http.StatusForbidden},
+		{digest[:20], false, http.StatusBadRequest},
+	}
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, tc := range tt {
+		if SHA1Validator(body, tc.sig, "supersecret") && tc.sig == digest {
+			t.Error("expected SHA1Validator to require the sha1= prefix")
+		}
+
+		if ok := SHA1LegacyValidator(body, tc.sig, "supersecret"); ok != tc.valid {
+			t.Errorf("expected %v for %q; got %v", tc.valid, tc.sig, ok)
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		Handler(x, "supersecret", OptionSignaturePrefixes("sha1=", "")).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %q; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package hmacsig
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// GitlabTokenHeader is the header used by GitLab to send the shared
+	// secret token with each WebHook
+	GitlabTokenHeader = "X-Gitlab-Token"
+
+	// ShopifySignatureHeader is the header used by Shopify for their base64
+	// encoded SHA-256 WebHook signatures
+	ShopifySignatureHeader = "X-Shopify-Hmac-Sha256"
+
+	// StripeSignatureHeader is the header used by Stripe for their timestamped
+	// SHA-256 WebHook signatures
+	StripeSignatureHeader = "Stripe-Signature"
+
+	// StripeDefaultTolerance is the maximum age of a Stripe signature
+	// timestamp accepted by ProviderStripe
+	StripeDefaultTolerance = 5 * time.Minute
+
+	// SlackSignatureHeader is the header used by Slack for their SHA-256
+	// request signatures
+	SlackSignatureHeader = "X-Slack-Signature"
+
+	// SlackTimestampHeader is the header used by Slack for the timestamp
+	// included in their request signatures
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+
+	// SlackDefaultTolerance is the maximum age of a Slack request timestamp
+	// accepted by ProviderSlack
+	SlackDefaultTolerance = 5 * time.Minute
+)
+
+// Provider is a preset configuring the header, validator and signature format
+// used by a particular WebHook provider
+type Provider struct {
+	// Name identifies the provider, e.g. "github"
+	Name string
+
+	// Options are applied in order by OptionProvider
+	Options []Option
+}
+
+var (
+	// ProviderGitHub configures GitHub's SHA-1 X-Hub-Signature
+	ProviderGitHub = Provider{"github", []Option{optionDefaultsSHA1}}
+
+	// ProviderGitHub256 configures GitHub's SHA-256 X-Hub-Signature-256
+	ProviderGitHub256 = Provider{"github256", []Option{OptionDefaultsSHA256}}
+
+	// ProviderGitLab configures GitLab's X-Gitlab-Token, which carries the
+	// secret itself rather than an HMAC
+	ProviderGitLab = Provider{"gitlab", []Option{
+		OptionHeader(GitlabTokenHeader),
+		OptionSignatureValidator(TokenValidator),
+	}}
+
+	// ProviderShopify configures Shopify's base64 encoded SHA-256
+	// X-Shopify-Hmac-Sha256
+	ProviderShopify = Provider{"shopify", []Option{
+		OptionHeader(ShopifySignatureHeader),
+		OptionSignatureValidator(SHA256Base64Validator),
+	}}
+
+	// ProviderStripe configures Stripe's timestamped Stripe-Signature,
+	// rejecting signatures older than StripeDefaultTolerance as measured by
+	// the clock configured by OptionClock
+	ProviderStripe = Provider{"stripe", []Option{
+		OptionHeader(StripeSignatureHeader),
+		optionStripeValidator(StripeDefaultTolerance),
+	}}
+
+	// ProviderSlack configures Slack's X-Slack-Signature, rejecting requests
+	// whose X-Slack-Request-Timestamp is older than SlackDefaultTolerance as
+	// measured by the clock configured by OptionClock
+	ProviderSlack = Provider{"slack", []Option{
+		OptionHeader(SlackSignatureHeader),
+		optionSlackValidator(SlackDefaultTolerance),
+	}}
+)
+
+// OptionProvider applies the options of the given Provider preset. Options
+// following it may override individual settings, for instance the header.
+func OptionProvider(p Provider) Option {
+	return func(mux *hmacSig) {
+		for _, option := range p.Options {
+			option(mux)
+		}
+	}
+}
+
+// optionStripeValidator configures NewStripeValidatorClock with the given
+// tolerance and the clock configured by OptionClock, before or after it
+func optionStripeValidator(tolerance time.Duration) Option {
+	return func(mux *hmacSig) {
+		OptionSignatureValidator(NewStripeValidatorClock(tolerance, mux.clock))(mux)
+	}
+}
+
+// optionSlackValidator configures NewSlackValidatorClock with the given
+// tolerance and the clock configured by OptionClock, before or after it
+func optionSlackValidator(tolerance time.Duration) Option {
+	return func(mux *hmacSig) {
+		OptionRequestValidator(NewSlackValidatorClock(tolerance, mux.clock))(mux)
+	}
+}
+
+// optionDefaultsSHA1 configures the HTTP Header and Validator to the defaults
+// used by GitHub for SHA1 validation, as used by Handler
+func optionDefaultsSHA1(mux *hmacSig) {
+	mux.header = GithubSignatureHeader
+	mux.validator = SignatureValidator(SHA1Validator).errorFunc()
+	mux.format = &signatureFormat{prefix: "sha1=", size: sha1.Size}
+	mux.hash = sha1.New
+}
+
+// TokenValidator implements the interface SignatureValidator for providers
+// such as GitLab which send the shared secret itself rather than an HMAC. The
+// comparison is constant time regardless of the lengths involved.
+func TokenValidator(body []byte, sig, secret string) bool {
+	s := sha256.Sum256([]byte(sig))
+	e := sha256.Sum256([]byte(secret))
+
+	return subtle.ConstantTimeCompare(s[:], e[:]) == 1
+}
+
+// SHA256Base64Validator implements the interface SignatureValidator and
+// SHA-256 HMAC validation of a standard base64 encoded digest without any
+// prefix, as used by Shopify
+func SHA256Base64Validator(body []byte, sig, secret string) bool {
+	sigDigest, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	return equalDigest(sha256HMAC.digest(body, []byte(secret)), sigDigest)
+}
+
+// NewStripeValidator returns a SignatureValidator for Stripe's
+// "t=<timestamp>,v1=<signature>" format, where the signature is the hex
+// encoded SHA-256 HMAC of the timestamp, a period, and the body. Signatures
+// with a timestamp further than tolerance from the current time are rejected.
+// A tolerance of zero disables the timestamp check.
+func NewStripeValidator(tolerance time.Duration) SignatureValidator {
+	return NewStripeValidatorClock(tolerance, time.Now)
+}
+
+// NewStripeValidatorClock is NewStripeValidator, measuring the age of
+// timestamps against the time returned by now rather than time.Now
+func NewStripeValidatorClock(tolerance time.Duration, now func() time.Time) SignatureValidator {
+	return func(body []byte, sig, secret string) bool {
+		var ts string
+		var sigs [][]byte
+		for _, part := range strings.Split(sig, ",") {
+			k, v, _ := strings.Cut(part, "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				if d, err := hex.DecodeString(v); err == nil {
+					sigs = append(sigs, d)
+				}
+			}
+		}
+
+		t, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+
+		if tolerance > 0 && !withinTolerance(t, tolerance, now) {
+			return false
+		}
+
+		expected := sha256HMAC.digest(stripePayload(ts, body), []byte(secret))
+
+		ok := false
+		for _, s := range sigs {
+			if equalDigest(expected, s) {
+				ok = true
+			}
+		}
+
+		return ok
+	}
+}
+
+// SignStripe computes the Stripe-Signature header value for body at time t as
+// expected by NewStripeValidator
+func SignStripe(body []byte, secret string, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	return "t=" + ts + "," + Sign(stripePayload(ts, body), secret, sha256.New, "v1=")
+}
+
+func stripePayload(ts string, body []byte) []byte {
+	return append([]byte(ts+"."), body...)
+}
+
+// NewSlackValidator returns a RequestValidator for Slack's request signatures,
+// "v0=" followed by the hex encoded SHA-256 HMAC of "v0:", the
+// X-Slack-Request-Timestamp header, ":" and the body. Requests with a timestamp
+// further than tolerance from the current time are rejected. A tolerance of
+// zero disables the timestamp check.
+func NewSlackValidator(tolerance time.Duration) RequestValidator {
+	return NewSlackValidatorClock(tolerance, time.Now)
+}
+
+// NewSlackValidatorClock is NewSlackValidator, measuring the age of
+// timestamps against the time returned by now rather than time.Now
+func NewSlackValidatorClock(tolerance time.Duration, now func() time.Time) RequestValidator {
+	return func(r *http.Request, body []byte, sig, secret string) bool {
+		ts := r.Header.Get(SlackTimestampHeader)
+		t, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+
+		if tolerance > 0 && !withinTolerance(t, tolerance, now) {
+			return false
+		}
+
+		return validate(slackPayload(ts, body), sig, secret, sha256HMAC, "v0=")
+	}
+}
+
+// SignSlack computes the X-Slack-Signature header value for body at time t as
+// expected by NewSlackValidator. The X-Slack-Request-Timestamp header must be
+// set to the Unix time of t.
+func SignSlack(body []byte, secret string, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	return Sign(slackPayload(ts, body), secret, sha256.New, "v0=")
+}
+
+func slackPayload(ts string, body []byte) []byte {
+	return append([]byte("v0:"+ts+":"), body...)
+}
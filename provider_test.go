@@ -0,0 +1,166 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProvider(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	shopify := base64.StdEncoding.EncodeToString(digest(body, "supersecret", sha256.New))
+
+	tt := []struct {
+		name     string
+		provider Provider
+		options  []Option
+		header   string
+		sig      string
+		status   int
+	}{
+		{"github", ProviderGitHub, nil, GithubSignatureHeader, SignSHA1(body, "supersecret"), http.StatusOK},
+		{"github wrong", ProviderGitHub, nil, GithubSignatureHeader, SignSHA1(body, "wrong"), http.StatusForbidden},
+		{"github256", ProviderGitHub256, nil, GithubSignatureHeader256, SignSHA256(body, "supersecret"), http.StatusOK},
+		{"github256 malformed", ProviderGitHub256, nil, GithubSignatureHeader256, "sha256=zz", http.StatusBadRequest},
+		{"gitlab", ProviderGitLab, nil, GitlabTokenHeader, "supersecret", http.StatusOK},
+		{"gitlab wrong", ProviderGitLab, nil, GitlabTokenHeader, "supersecret2", http.StatusForbidden},
+		{"shopify", ProviderShopify, nil, ShopifySignatureHeader, shopify, http.StatusOK},
+		{"shopify hex", ProviderShopify, nil, ShopifySignatureHeader, SignSHA256(body, "supersecret"), http.StatusForbidden},
+		{"stripe", ProviderStripe, nil, StripeSignatureHeader, SignStripe(body, "supersecret", time.Now()), http.StatusOK},
+		{"stripe stale", ProviderStripe, nil, StripeSignatureHeader, SignStripe(body, "supersecret", time.Now().Add(-time.Hour)), http.StatusForbidden},
+		{"stripe wrong", ProviderStripe, nil, StripeSignatureHeader, SignStripe(body, "wrong", time.Now()), http.StatusForbidden},
+		{"override header", ProviderGitHub256, []Option{OptionHeader("X-Sig")}, "X-Sig", SignSHA256(body, "supersecret"), http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(tc.header, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", append([]Option{OptionProvider(tc.provider)}, tc.options...)...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+	}
+}
+
+func TestStripeValidator(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	tt := []struct {
+		sig   string
+		valid bool
+	}{
+		{SignStripe(body, "supersecret", now), true},
+		{SignStripe(body, "supersecret", now) + ",v0=deadbeef", true},
+		{SignStripe(body, "old", now) + "," + SignStripe(body, "supersecret", now)[len("t=0000000000,"):], true},
+		{"v1=" + SignStripe(body, "supersecret", now)[len("t=0000000000,v1="):], false},
+		{"t=abc," + SignStripe(body, "supersecret", now)[len("t=0000000000,"):], false},
+		{"", false},
+	}
+
+	v := NewStripeValidator(time.Minute)
+	for _, tc := range tt {
+		if ok := v(body, tc.sig, "supersecret"); ok != tc.valid {
+			t.Errorf("expected %v for '%v'; got %v", tc.valid, tc.sig, ok)
+		}
+	}
+
+	if !NewStripeValidator(0)(body, SignStripe(body, "supersecret", now.Add(-24*time.Hour)), "supersecret") {
+		t.Error("expected zero tolerance to disable the timestamp check")
+	}
+}
+
+func TestProviderClock(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Date(2019, 4, 21, 11, 36, 17, 0, time.UTC)
+	clock := OptionClock(func() time.Time { return now })
+
+	tt := []struct {
+		name     string
+		provider Provider
+		header   string
+		sig      string
+		signedAt time.Time
+		status   int
+	}{
+		{"stripe", ProviderStripe, StripeSignatureHeader, SignStripe(body, "supersecret", now), now, http.StatusOK},
+		{"stripe now", ProviderStripe, StripeSignatureHeader, SignStripe(body, "supersecret", time.Now()), time.Now(), http.StatusForbidden},
+		{"slack", ProviderSlack, SlackSignatureHeader, SignSlack(body, "supersecret", now), now, http.StatusOK},
+		{"slack now", ProviderSlack, SlackSignatureHeader, SignSlack(body, "supersecret", time.Now()), time.Now(), http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		for _, options := range [][]Option{{clock, OptionProvider(tc.provider)}, {OptionProvider(tc.provider), clock}} {
+			req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+			req.Header.Set(tc.header, tc.sig)
+			req.Header.Set(SlackTimestampHeader, strconv.FormatInt(tc.signedAt.Unix(), 10))
+			rec := httptest.NewRecorder()
+
+			x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+
+			Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+			}
+		}
+	}
+}
+
+func TestStripeValidatorClock(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Date(2019, 4, 21, 11, 36, 17, 0, time.UTC)
+
+	tt := []struct {
+		signedAt time.Time
+		valid    bool
+	}{
+		{now, true},
+		{now.Add(-5 * time.Minute), true},
+		{now.Add(5 * time.Minute), true},
+		{now.Add(-5*time.Minute - time.Second), false},
+		{now.Add(5*time.Minute + time.Second), false},
+		{time.Now(), false},
+	}
+
+	v := NewStripeValidatorClock(StripeDefaultTolerance, func() time.Time { return now })
+	for _, tc := range tt {
+		if ok := v(body, SignStripe(body, "supersecret", tc.signedAt), "supersecret"); ok != tc.valid {
+			t.Errorf("expected %v for signature at %v; got %v", tc.valid, tc.signedAt, ok)
+		}
+	}
+}
+
+func TestTokenValidator(t *testing.T) {
+	tt := []struct {
+		sig   string
+		valid bool
+	}{
+		{"supersecret", true},
+		{"supersecre", false},
+		{"supersecret ", false},
+		{"", false},
+	}
+
+	for _, tc := range tt {
+		if ok := TokenValidator(nil, tc.sig, "supersecret"); ok != tc.valid {
+			t.Errorf("expected %v for '%v'; got %v", tc.valid, tc.sig, ok)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package hmacsig
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Reconfigurable is HMAC signature validating middleware, as returned by
+// Handler, whose secret, validator and header may be changed while it is
+// serving requests, for instance to rotate the secret or upgrade the
+// algorithm without restarting the server. It is safe for concurrent use.
+//
+// Each change rebuilds the middleware from the options it was created with
+// followed by the changes made so far, and atomically swaps it in. Requests
+// being served complete with the configuration they began with. To rotate
+// secrets alone, OptionSecretStore is lighter weight.
+type Reconfigurable struct {
+	v atomic.Value
+
+	mu        sync.Mutex
+	h         http.Handler
+	secret    string
+	secretSet bool
+	options   []Option
+	validator SignatureValidator
+	header    string
+}
+
+// NewReconfigurable returns a Reconfigurable middleware wrapping h, checking
+// its configuration as NewHandler does
+func NewReconfigurable(h http.Handler, secret string, options ...Option) (*Reconfigurable, error) {
+	rc := &Reconfigurable{
+		h:       h,
+		secret:  secret,
+		options: append([]Option(nil), options...),
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if err := rc.rebuild(); err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+// ServeHTTP implements http.Handler with the current configuration
+func (rc *Reconfigurable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.v.Load().(*hmacSig).ServeHTTP(w, r)
+}
+
+// SetSecret replaces the secret signatures are validated against, as
+// OptionSecrets with a single secret would. Secret functions and stores
+// configured by OptionSecretFunc or OptionSecretStore take precedence over it.
+// If the resulting configuration is invalid, such as for an empty secret, the
+// error is returned and the current configuration kept.
+func (rc *Reconfigurable) SetSecret(secret string) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	prev, prevSet := rc.secret, rc.secretSet
+	rc.secret, rc.secretSet = secret, true
+
+	return rc.rebuildOr(func() { rc.secret, rc.secretSet = prev, prevSet })
+}
+
+// SetValidator replaces the SignatureValidator, as OptionSignatureValidator
+// would, clearing the signature format. If the resulting configuration is
+// invalid the error is returned and the current configuration kept.
+func (rc *Reconfigurable) SetValidator(validator SignatureValidator) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	prev := rc.validator
+	rc.validator = validator
+
+	return rc.rebuildOr(func() { rc.validator = prev })
+}
+
+// SetHeader replaces the HTTP header the signature is read from, as
+// OptionHeader would. If the resulting configuration is invalid the error is
+// returned and the current configuration kept.
+func (rc *Reconfigurable) SetHeader(header string) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	prev := rc.header
+	rc.header = header
+
+	return rc.rebuildOr(func() { rc.header = prev })
+}
+
+// rebuildOr rebuilds the middleware, calling undo if its configuration is
+// invalid. rc.mu must be held.
+func (rc *Reconfigurable) rebuildOr(undo func()) error {
+	err := rc.rebuild()
+	if err != nil {
+		undo()
+	}
+
+	return err
+}
+
+// rebuild builds the middleware from the current configuration and swaps it
+// in if valid. rc.mu must be held.
+func (rc *Reconfigurable) rebuild() error {
+	options := append([]Option(nil), rc.options...)
+	if rc.secretSet {
+		options = append(options, OptionSecrets(rc.secret))
+	}
+	if rc.header != "" {
+		options = append(options, OptionHeader(rc.header))
+	}
+	if rc.validator != nil {
+		options = append(options, OptionSignatureValidator(rc.validator))
+	}
+
+	sig := newHMACSig(rc.h, rc.secret, options...)
+	if err := sig.validateConfig(); err != nil {
+		return err
+	}
+
+	rc.v.Store(sig)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestReconfigurable(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rc, err := NewReconfigurable(x, "oldsecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(step, header, sig string, status int) {
+		t.Helper()
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(header, sig)
+		rec := httptest.NewRecorder()
+
+		rc.ServeHTTP(rec, req)
+
+		if rec.Code != status {
+			t.Errorf("%s: expected status %d for '%s'; got %d", step, status, sig, rec.Code)
+		}
+	}
+
+	check("initial", GithubSignatureHeader, SignSHA1(body, "oldsecret"), http.StatusOK)
+
+	if err := rc.SetSecret("newsecret"); err != nil {
+		t.Fatal(err)
+	}
+	check("rotated", GithubSignatureHeader, SignSHA1(body, "oldsecret"), http.StatusForbidden)
+	check("rotated", GithubSignatureHeader, SignSHA1(body, "newsecret"), http.StatusOK)
+
+	if err := rc.SetSecret(""); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("expected '%v'; got '%v'", ErrEmptySecret, err)
+	}
+	check("empty secret rejected", GithubSignatureHeader, SignSHA1(body, "newsecret"), http.StatusOK)
+
+	if err := rc.SetValidator(SHA256Validator); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.SetHeader(GithubSignatureHeader256); err != nil {
+		t.Fatal(err)
+	}
+	check("upgraded", GithubSignatureHeader, SignSHA1(body, "newsecret"), http.StatusForbidden)
+	check("upgraded", GithubSignatureHeader256, SignSHA256(body, "newsecret"), http.StatusOK)
+
+	if _, err := NewReconfigurable(x, ""); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("expected '%v'; got '%v'", ErrEmptySecret, err)
+	}
+}
+
+func TestReconfigurableOptions(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rc, err := NewReconfigurable(x, "", OptionSecrets("oldsecret", "newsecret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rc.SetHeader("X-Sig"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"oldsecret", "newsecret"} {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("X-Sig", SignSHA1(body, secret))
+		rec := httptest.NewRecorder()
+
+		rc.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected secrets configured by option kept for '%s'; got %d", secret, rec.Code)
+		}
+	}
+}
+
+func TestReconfigurableConcurrent(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rc, err := NewReconfigurable(x, "supersecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				rc.SetSecret("supersecret")
+				rc.SetHeader(GithubSignatureHeader)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+				req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+				rec := httptest.NewRecorder()
+
+				rc.ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusOK {
+					t.Errorf("expected status OK; got %d", rec.Code)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
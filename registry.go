@@ -0,0 +1,71 @@
+package hmacsig
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownValidator is returned by NewHandler when OptionValidatorName names
+// no registered validator. It wraps ErrNoValidator.
+var ErrUnknownValidator = fmt.Errorf("%w: unknown validator name", ErrNoValidator)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]SignatureValidator{
+		"sha1":   SHA1Validator,
+		"sha224": SHA224Validator,
+		"sha256": SHA256Validator,
+		"sha384": SHA384Validator,
+	}
+)
+
+// RegisterValidator registers v under name, matched case-insensitively,
+// replacing any validator already registered under it. The built-in "sha1",
+// "sha224", "sha256" and "sha384" validators are registered by default.
+//
+// Registration is safe for concurrent use but is expected to happen at init
+// time, before handlers looking validators up by name are built.
+func RegisterValidator(name string, v SignatureValidator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[strings.ToLower(name)] = v
+}
+
+// ValidatorByName returns the validator registered under name, matched
+// case-insensitively, and whether one is registered
+func ValidatorByName(name string) (SignatureValidator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	v, ok := registry[strings.ToLower(name)]
+
+	return v, ok
+}
+
+// OptionValidatorName configures the validator registered under name, as by
+// OptionSignatureValidator, allowing the algorithm to be read from
+// configuration, e.g. "sha256". The header is not changed.
+//
+// If no validator is registered under name NewHandler reports
+// ErrUnknownValidator, and Handler, which does not check its configuration,
+// instead rejects every request with it.
+func OptionValidatorName(name string) Option {
+	return func(mux *hmacSig) {
+		v, ok := ValidatorByName(name)
+		if !ok {
+			err := fmt.Errorf("%w: %q", ErrUnknownValidator, name)
+			mux.configErr = err
+			mux.validator = func(r *http.Request, body []byte, sig, secret string) error {
+				return err
+			}
+			mux.format = nil
+			mux.hash = nil
+			return
+		}
+
+		OptionSignatureValidator(v)(mux)
+	}
+}
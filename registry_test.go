@@ -0,0 +1,99 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidatorByName(t *testing.T) {
+	body := []byte("This body is super")
+
+	RegisterValidator("SHA512", NewValidator(sha512.New, "sha512="))
+
+	tt := []struct {
+		name string
+		sig  string
+	}{
+		{"sha1", SignSHA1(body, "EvenDifferentKey")},
+		{"SHA256", SignSHA256(body, "EvenDifferentKey")},
+		{"sha384", SignSHA384(body, "EvenDifferentKey")},
+		{"sha512", Sign(body, "EvenDifferentKey", sha512.New, "sha512=")},
+	}
+
+	for _, tc := range tt {
+		v, ok := ValidatorByName(tc.name)
+		if !ok {
+			t.Errorf("expected validator registered for '%s'", tc.name)
+			continue
+		}
+
+		if !v(body, tc.sig, "EvenDifferentKey") {
+			t.Errorf("expected '%s' to validate '%s'", tc.name, tc.sig)
+		}
+	}
+
+	if _, ok := ValidatorByName("md5"); ok {
+		t.Error("expected no validator registered for 'md5'")
+	}
+}
+
+func TestOptionValidatorName(t *testing.T) {
+	body := []byte("This body is super")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	h, err := NewHandler(x, "EvenDifferentKey", OptionHeader(GithubSignatureHeader256), OptionValidatorName("sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader256, SignSHA256(body, "EvenDifferentKey"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %d", rec.Code)
+	}
+
+	if _, err := NewHandler(x, "EvenDifferentKey", OptionValidatorName("md5")); !errors.Is(err, ErrNoValidator) {
+		t.Errorf("expected ErrNoValidator for unknown name; got %v", err)
+	}
+}
+
+func TestOptionValidatorNameUnknown(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("should not be executed")
+	})
+
+	if _, err := NewHandler(x, "supersecret", OptionValidatorName("sha265")); !errors.Is(err, ErrUnknownValidator) {
+		t.Errorf("expected '%v'; got '%v'", ErrUnknownValidator, err)
+	}
+
+	var got error
+	failed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ErrorFromContext(r.Context())
+		DefaultVerifyFailedHandler(w, r)
+	})
+
+	body := []byte("This is the body of the request")
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+	rec := httptest.NewRecorder()
+
+	Handler(x, "supersecret", OptionValidatorName("sha265"), OptionVerifyFailedHandler(failed)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d; got %d", http.StatusForbidden, rec.Code)
+	}
+
+	if !errors.Is(got, ErrUnknownValidator) {
+		t.Errorf("expected reason '%v'; got '%v'", ErrUnknownValidator, got)
+	}
+}
@@ -0,0 +1,179 @@
+package hmacsig
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// GithubDeliveryHeader is the header used by GitHub to uniquely identify
+	// each WebHook delivery
+	GithubDeliveryHeader = "X-GitHub-Delivery"
+
+	// GithubEventHeader is the header used by GitHub to name the event type
+	// of each WebHook delivery
+	GithubEventHeader = "X-GitHub-Event"
+
+	// MsgReplayRejected is the message returned in the body when a request
+	// was rejected by replay protection
+	MsgReplayRejected = "Request rejected as a possible replay"
+)
+
+var (
+	// ErrInvalidTimestamp is the failure reason when the timestamp header
+	// configured by OptionTimestamp was missing or not a Unix timestamp
+	ErrInvalidTimestamp = errors.New("hmacsig: missing or invalid timestamp")
+
+	// ErrStaleTimestamp is the failure reason when the timestamp header
+	// configured by OptionTimestamp was outside the accepted window
+	ErrStaleTimestamp = errors.New("hmacsig: timestamp outside accepted window")
+
+	// ErrMissingDeliveryID is the failure reason when the delivery ID header
+	// configured by OptionNonceStore was missing
+	ErrMissingDeliveryID = errors.New("hmacsig: missing delivery id")
+
+	// ErrReplayed is the failure reason when the NonceStore configured by
+	// OptionNonceStore had already seen the delivery ID
+	ErrReplayed = errors.New("hmacsig: delivery id already seen")
+)
+
+// NonceStore records the delivery IDs of verified requests
+type NonceStore interface {
+	// Seen records id and reports whether it had already been recorded
+	Seen(id string) bool
+}
+
+// OptionTimestamp configures a header which must hold the Unix timestamp, in
+// seconds, the request was sent at. Verified requests with a missing timestamp
+// or one further than maxAge from the current time in either direction are
+// passed to the replay handler.
+//
+// The timestamp only protects against replay if the sender authenticates it,
+// for instance by including it in the signed content.
+func OptionTimestamp(header string, maxAge time.Duration) Option {
+	return func(mux *hmacSig) {
+		mux.timestampHeader = header
+		mux.timestampMaxAge = maxAge
+	}
+}
+
+// OptionClock configures the source of the current time OptionTimestamp,
+// ProviderStripe and ProviderSlack measure request timestamps against,
+// time.Now by default. It allows tests to fix the current time, and
+// deployments to correct for a skewed clock.
+func OptionClock(now func() time.Time) Option {
+	return func(mux *hmacSig) {
+		mux.now = now
+	}
+}
+
+// clock returns the current time from the source configured by OptionClock
+// at the time it is called
+func (xh *hmacSig) clock() time.Time {
+	return xh.now()
+}
+
+// OptionNonceStore configures a NonceStore consulted with the value of the
+// given delivery ID header, e.g. GithubDeliveryHeader, once a request is
+// verified. Requests missing the header or whose delivery ID was already seen
+// are passed to the replay handler.
+func OptionNonceStore(header string, store NonceStore) Option {
+	return func(mux *hmacSig) {
+		mux.nonceHeader = header
+		mux.nonceStore = store
+	}
+}
+
+// OptionReplayHandler configures the http.Handler called when a request is
+// rejected by replay protection, see OptionTimestamp and OptionNonceStore
+func OptionReplayHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.replayHandler = handler
+	}
+}
+
+// DefaultReplayHandler is the default response to a request rejected by
+// replay protection
+func DefaultReplayHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgReplayRejected, http.StatusForbidden)
+}
+
+// checkReplay applies the configured replay protection to a verified request
+func (xh *hmacSig) checkReplay(r *http.Request) error {
+	if xh.timestampHeader != "" {
+		ts, err := strconv.ParseInt(r.Header.Get(xh.timestampHeader), 10, 64)
+		if err != nil {
+			return ErrInvalidTimestamp
+		}
+
+		if !withinTolerance(ts, xh.timestampMaxAge, xh.now) {
+			return ErrStaleTimestamp
+		}
+	}
+
+	if xh.nonceStore != nil {
+		id := r.Header.Get(xh.nonceHeader)
+		if id == "" {
+			return ErrMissingDeliveryID
+		}
+
+		if xh.nonceStore.Seen(id) {
+			return ErrReplayed
+		}
+	}
+
+	return nil
+}
+
+// withinTolerance reports whether the Unix timestamp ts is no further than
+// tolerance from the time returned by now, in either direction
+func withinTolerance(ts int64, tolerance time.Duration, now func() time.Time) bool {
+	age := now().Sub(time.Unix(ts, 0))
+
+	return age <= tolerance && age >= -tolerance
+}
+
+// MemoryNonceStore is an in-memory NonceStore which forgets IDs after a TTL.
+// It is safe for concurrent use.
+type MemoryNonceStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore remembering IDs for ttl
+func NewMemoryNonceStore(ttl time.Duration) *MemoryNonceStore {
+	return &MemoryNonceStore{
+		ttl:       ttl,
+		seen:      make(map[string]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+// Seen implements NonceStore
+func (m *MemoryNonceStore) Seen(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastPrune) > m.ttl {
+		for k, exp := range m.seen {
+			if now.After(exp) {
+				delete(m.seen, k)
+			}
+		}
+		m.lastPrune = now
+	}
+
+	if exp, ok := m.seen[id]; ok && !now.After(exp) {
+		return true
+	}
+
+	m.seen[id] = now.Add(m.ttl)
+	return false
+}
@@ -0,0 +1,146 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReplayProtection(t *testing.T) {
+	body := []byte("This is the body of the request")
+	now := time.Now().Unix()
+
+	tt := []struct {
+		timestamp string
+		delivery  string
+		err       error
+	}{
+		{strconv.FormatInt(now, 10), "a", nil},
+		{strconv.FormatInt(now-30, 10), "b", nil},
+		{strconv.FormatInt(now+30, 10), "c", nil},
+		{strconv.FormatInt(now, 10), "a", ErrReplayed},
+		{strconv.FormatInt(now-600, 10), "d", ErrStaleTimestamp},
+		{strconv.FormatInt(now+600, 10), "e", ErrStaleTimestamp},
+		{"", "f", ErrInvalidTimestamp},
+		{"yesterday", "g", ErrInvalidTimestamp},
+		{strconv.FormatInt(now, 10), "", ErrMissingDeliveryID},
+	}
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	var got error
+	replay := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ErrorFromContext(r.Context())
+		DefaultReplayHandler(w, r)
+	})
+
+	xhs := Handler(x, "supersecret",
+		OptionTimestamp("X-Timestamp", time.Minute),
+		OptionNonceStore(GithubDeliveryHeader, NewMemoryNonceStore(time.Hour)),
+		OptionReplayHandler(replay))
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		req.Header.Set("X-Timestamp", tc.timestamp)
+		req.Header.Set(GithubDeliveryHeader, tc.delivery)
+		rec := httptest.NewRecorder()
+
+		got = nil
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if tc.err == nil && res.StatusCode != http.StatusOK {
+			t.Errorf("expected status OK for %s; got %v", tc.delivery, res.Status)
+		}
+
+		if !errors.Is(got, tc.err) {
+			t.Errorf("expected reason '%v' for %s; got '%v'", tc.err, tc.delivery, got)
+		}
+	}
+}
+
+func TestReplayClock(t *testing.T) {
+	body := []byte("This is the body of the request")
+	now := time.Date(2019, 4, 21, 11, 36, 17, 0, time.UTC)
+
+	tt := []struct {
+		timestamp time.Time
+		status    int
+	}{
+		{now, http.StatusOK},
+		{now.Add(-time.Minute), http.StatusOK},
+		{now.Add(time.Minute), http.StatusOK},
+		{now.Add(-time.Minute - time.Second), http.StatusForbidden},
+		{now.Add(time.Minute + time.Second), http.StatusForbidden},
+		{time.Now(), http.StatusForbidden},
+	}
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	xhs := Handler(x, "supersecret",
+		OptionTimestamp("X-Timestamp", time.Minute),
+		OptionClock(func() time.Time { return now }))
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+		req.Header.Set("X-Timestamp", strconv.FormatInt(tc.timestamp.Unix(), 10))
+		rec := httptest.NewRecorder()
+
+		xhs.ServeHTTP(rec, req)
+
+		if res := rec.Result(); res.StatusCode != tc.status {
+			t.Errorf("expected status %d for %v; got %v", tc.status, tc.timestamp, res.Status)
+		}
+	}
+}
+
+func TestReplayProtectionUnverified(t *testing.T) {
+	store := NewMemoryNonceStore(time.Hour)
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	xhs := Handler(x, "supersecret", OptionNonceStore(GithubDeliveryHeader, store))
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+	req.Header.Set(GithubSignatureHeader, SignSHA1([]byte("This is the body of the request"), "wrongsecret"))
+	req.Header.Set(GithubDeliveryHeader, "a")
+	xhs.ServeHTTP(httptest.NewRecorder(), req)
+
+	if store.Seen("a") {
+		t.Error("expected unverified request not to be recorded")
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore(20 * time.Millisecond)
+
+	if store.Seen("a") {
+		t.Error("expected a not to have been seen")
+	}
+
+	if !store.Seen("a") {
+		t.Error("expected a to have been seen")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if store.Seen("a") {
+		t.Error("expected a to have expired")
+	}
+
+	if len(store.seen) != 1 {
+		t.Errorf("expected expired ids to be pruned; got %d ids", len(store.seen))
+	}
+}
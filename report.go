@@ -0,0 +1,54 @@
+package hmacsig
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// OptionReportOnly configures the middleware not to enforce verification:
+// requests are verified and the outcome reported to the Logger and observers
+// as usual, but requests which would be rejected are passed to the wrapped
+// handler regardless, for instance to measure how many senders do not yet sign
+// before enforcing. Every such request is logged as not enforced, and the
+// reason it would have been rejected is available to the wrapped handler via
+// ErrorFromContext.
+//
+// Rejections reported as ResultMissing or ResultFailed are waived, including
+// those for the content type, a missing Content-Length or no route matching
+// the path. Requests whose body cannot be read, exceeds the limit configured
+// by OptionMaxBodyBytes or is not read in time, or whose secret cannot be
+// resolved, are still rejected, as are requests beyond the limit configured
+// by OptionMaxConcurrentReads. As the body must be available to the wrapped
+// handler, OptionSpillToDisk and OptionReuseGetBody have no effect.
+func OptionReportOnly(mux *hmacSig) {
+	mux.reportOnly = true
+}
+
+// waives reports whether the rejection of a request for reason is waived by
+// OptionReportOnly
+func (xh *hmacSig) waives(reason error) bool {
+	if !xh.reportOnly {
+		return false
+	}
+
+	res := resultFor(reason)
+
+	return res == ResultMissing || res == ResultFailed
+}
+
+// withBufferedBody returns a shallow copy of r with its body replaced by b,
+// so that it remains readable should its rejection be waived
+func (xh *hmacSig) withBufferedBody(r *http.Request, b []byte) *http.Request {
+	if !xh.reportOnly {
+		return r
+	}
+
+	r = r.WithContext(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return r
+}
@@ -0,0 +1,80 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportOnly(t *testing.T) {
+	body := "This is the body of the request"
+
+	tt := []struct {
+		name    string
+		sig     string
+		options []Option
+		status  int
+		result  Result
+		reason  error
+		chunked bool
+	}{
+		{"valid", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", nil, http.StatusOK, ResultOK, nil, false},
+		{"mismatch", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", nil, http.StatusOK, ResultFailed, ErrSignatureMismatch, false},
+		{"malformed", "sha1=zz", nil, http.StatusOK, ResultFailed, ErrMalformedSignature, false},
+		{"missing", "", nil, http.StatusOK, ResultMissing, ErrMissingSignature, false},
+		{"mismatch, spill ignored", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", []Option{OptionSpillToDisk(4, t.TempDir())}, http.StatusOK, ResultFailed, ErrSignatureMismatch, false},
+		{"too large, enforced", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionMaxBodyBytes(4)}, http.StatusRequestEntityTooLarge, ResultError, ErrBodyTooLarge, false},
+		{"content type", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionContentTypes("application/json")}, http.StatusOK, ResultFailed, ErrUnsupportedMediaType, false},
+		{"length required", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionRequireContentLength}, http.StatusOK, ResultFailed, ErrLengthRequired, true},
+		{"no route", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", []Option{OptionRoutes(Route{Path: "/github", Secret: "supersecret"})}, http.StatusOK, ResultFailed, ErrNoRoute, false},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(body)))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != body {
+				t.Errorf("%s: expected handler to read '%s'; got '%s'", tc.name, body, b)
+			}
+
+			if err := ErrorFromContext(r.Context()); !errors.Is(err, tc.reason) {
+				t.Errorf("%s: expected reason %v; got %v", tc.name, tc.reason, err)
+			}
+
+			w.Write([]byte("ok"))
+		})
+
+		var result Result
+		var logs strings.Builder
+		options := append([]Option{
+			OptionReportOnly,
+			OptionLogger(log.New(&logs, "", 0)),
+			OptionObserver(func(res Result, r *http.Request) { result = res }),
+		}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if result != tc.result {
+			t.Errorf("%s: expected result %v; got %v", tc.name, tc.result, result)
+		}
+
+		waived := tc.reason != nil && tc.status == http.StatusOK
+		if logged := strings.Contains(logs.String(), "not enforced"); logged != waived {
+			t.Errorf("%s: expected not enforced logged %v; got '%s'", tc.name, waived, logs.String())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package hmacsig
+
+import (
+	"hash"
+	"net/http"
+)
+
+// RequestValidator validates a request against its signature and the
+// servers secret, for schemes signing more than the body alone.
+//
+// The request body has already been consumed and is passed as body; r.Body
+// must not be read. r should be treated as read-only.
+type RequestValidator func(r *http.Request, body []byte, sig, secret string) bool
+
+// errorFunc adapts the RequestValidator to a validatorFunc, reporting
+// ErrSignatureMismatch when it returns false
+func (v RequestValidator) errorFunc() validatorFunc {
+	if v == nil {
+		return nil
+	}
+
+	return func(r *http.Request, body []byte, sig, secret string) error {
+		if !v(r, body, sig, secret) {
+			return ErrSignatureMismatch
+		}
+
+		return nil
+	}
+}
+
+// OptionRequestValidator configures a RequestValidator validated against in
+// place of the SignatureValidator, allowing arbitrary signing inputs to be
+// constructed from the request.
+//
+// As with OptionSignatureValidator, this clears the signature format.
+func OptionRequestValidator(validator RequestValidator) Option {
+	return func(mux *hmacSig) {
+		mux.validator = validator.errorFunc()
+		mux.format = nil
+		mux.hash = nil
+	}
+}
+
+// CanonicalRequest returns the canonical signing input of a request, its
+// method, path and body separated by newlines: "METHOD\nPATH\nBODY"
+func CanonicalRequest(method, path string, body []byte) []byte {
+	c := make([]byte, 0, len(method)+len(path)+len(body)+2)
+	c = append(c, method...)
+	c = append(c, '\n')
+	c = append(c, path...)
+	c = append(c, '\n')
+
+	return append(c, body...)
+}
+
+// NewCanonicalValidator returns a RequestValidator for signatures consisting
+// of prefix followed by the hex encoded HMAC, computed with h, of the
+// CanonicalRequest of the request's method, URL path and body. Signatures may
+// be created by passing the CanonicalRequest to Sign.
+func NewCanonicalValidator(h func() hash.Hash, prefix string) RequestValidator {
+	m := newHMACHash(h)
+
+	return func(r *http.Request, body []byte, sig, secret string) bool {
+		return validate(CanonicalRequest(r.Method, r.URL.Path, body), sig, secret, m, prefix)
+	}
+}
@@ -0,0 +1,114 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCanonicalRequest(t *testing.T) {
+	c := string(CanonicalRequest("POST", "/hooks/a", []byte("body")))
+	if c != "POST\n/hooks/a\nbody" {
+		t.Errorf("expected canonical request 'POST\\n/hooks/a\\nbody'; got '%v'", c)
+	}
+}
+
+func TestRequestValidator(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		method string
+		path   string
+		sig    string
+		status int
+	}{
+		{"POST", "/hooks/a", Sign(CanonicalRequest("POST", "/hooks/a", body), "supersecret", sha256.New, "sha256="), http.StatusOK},
+		{"PUT", "/hooks/a", Sign(CanonicalRequest("POST", "/hooks/a", body), "supersecret", sha256.New, "sha256="), http.StatusForbidden},
+		{"POST", "/hooks/b", Sign(CanonicalRequest("POST", "/hooks/a", body), "supersecret", sha256.New, "sha256="), http.StatusForbidden},
+		{"POST", "/hooks/a", SignSHA256(body, "supersecret"), http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler256(x, "supersecret", OptionRequestValidator(NewCanonicalValidator(sha256.New, "sha256=")))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d for %s %s; got %v", tc.status, tc.method, tc.path, res.Status)
+		}
+	}
+}
+
+func TestProviderSlack(t *testing.T) {
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J")
+	now := time.Now()
+
+	tt := []struct {
+		timestamp time.Time
+		signedAt  time.Time
+		secret    string
+		status    int
+	}{
+		{now, now, "supersecret", http.StatusOK},
+		{now, now, "wrongsecret", http.StatusForbidden},
+		{now.Add(time.Second), now, "supersecret", http.StatusForbidden},
+		{now.Add(-time.Hour), now.Add(-time.Hour), "supersecret", http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest("POST", "/slack", bytes.NewReader(body))
+		req.Header.Set(SlackSignatureHeader, SignSlack(body, tc.secret, tc.signedAt))
+		req.Header.Set(SlackTimestampHeader, strconv.FormatInt(tc.timestamp.Unix(), 10))
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "supersecret", OptionProvider(ProviderSlack))
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
+		}
+	}
+}
+
+func TestSlackValidatorClock(t *testing.T) {
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J")
+	now := time.Date(2019, 4, 21, 11, 36, 17, 0, time.UTC)
+
+	tt := []struct {
+		signedAt time.Time
+		valid    bool
+	}{
+		{now, true},
+		{now.Add(-5 * time.Minute), true},
+		{now.Add(-5*time.Minute - time.Second), false},
+		{now.Add(5*time.Minute + time.Second), false},
+		{time.Now(), false},
+	}
+
+	v := NewSlackValidatorClock(SlackDefaultTolerance, func() time.Time { return now })
+	for _, tc := range tt {
+		req := httptest.NewRequest("POST", "/slack", bytes.NewReader(body))
+		req.Header.Set(SlackTimestampHeader, strconv.FormatInt(tc.signedAt.Unix(), 10))
+
+		if ok := v(req, body, SignSlack(body, "supersecret", tc.signedAt), "supersecret"); ok != tc.valid {
+			t.Errorf("expected %v for signature at %v; got %v", tc.valid, tc.signedAt, ok)
+		}
+	}
+}
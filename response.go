@@ -0,0 +1,115 @@
+package hmacsig
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// OptionResponseObserver configures a function called once per verified
+// request, as by OptionObserver, but only once the request has completed,
+// additionally passed the status code and number of body bytes written to the
+// response by the failure handler or the wrapped handler, for instance to
+// label metrics by status.
+//
+// To capture them the http.ResponseWriter is wrapped. The wrapper passes
+// Flush and Hijack through to the underlying http.ResponseWriter where it
+// implements http.Flusher or http.Hijacker, and Unwrap returns it. The status
+// is http.StatusOK if the handler wrote nothing, and zero if it hijacked the
+// connection before writing. Skipped requests are not observed.
+func OptionResponseObserver(fn func(result Result, r *http.Request, status int, written int64)) Option {
+	return func(mux *hmacSig) {
+		mux.responseObserver = fn
+	}
+}
+
+// responseWriter is an http.ResponseWriter capturing the status and number of
+// bytes written, along with the Result and request observed for it
+type responseWriter struct {
+	http.ResponseWriter
+
+	status   int
+	written  int64
+	hijacked bool
+
+	observed bool
+	result   Result
+	r        *http.Request
+}
+
+// WriteHeader implements http.ResponseWriter, capturing the first
+// non-informational status
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 && code >= 200 {
+		rw.status = code
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter, counting the bytes written
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(p)
+	rw.written += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying http.ResponseWriter does
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, returning http.ErrNotSupported if the
+// underlying http.ResponseWriter does not
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+
+	return conn, brw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// observe records the Result and request to report once the request has
+// completed
+func (rw *responseWriter) observe(result Result, r *http.Request) {
+	rw.observed = true
+	rw.result = result
+	rw.r = r
+}
+
+// report calls fn with the observed Result and the captured response, if the
+// request was observed
+func (rw *responseWriter) report(fn func(result Result, r *http.Request, status int, written int64)) {
+	if !rw.observed {
+		return
+	}
+
+	status := rw.status
+	if status == 0 && !rw.hijacked {
+		status = http.StatusOK
+	}
+
+	fn(rw.result, rw.r, status, rw.written)
+}
@@ -0,0 +1,126 @@
+package hmacsig
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseObserver(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		sig     string
+		method  string
+		handler http.HandlerFunc
+		result  Result
+		status  int
+		written int64
+		called  bool
+	}{
+		{SignSHA1(body, "supersecret"), "POST", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}, ResultOK, http.StatusOK, 2, true},
+		{SignSHA1(body, "supersecret"), "POST", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}, ResultOK, http.StatusAccepted, 0, true},
+		{SignSHA1(body, "supersecret"), "POST", func(w http.ResponseWriter, r *http.Request) {
+		}, ResultOK, http.StatusOK, 0, true},
+		{SignSHA1(body, "supersecret"), "POST", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusCreated)
+		}, ResultOK, http.StatusCreated, 0, true},
+		{SignSHA1(body, "wrongsecret"), "POST", nil, ResultFailed, http.StatusForbidden, int64(len(MsgFailedHMAC) + 1), true},
+		{"", "POST", nil, ResultMissing, http.StatusBadRequest, int64(len(MsgMissingSignature) + 1), true},
+		{"", "GET", func(w http.ResponseWriter, r *http.Request) {}, 0, 0, 0, false},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		called := false
+		observer := func(result Result, r *http.Request, status int, written int64) {
+			called = true
+
+			if result != tc.result || status != tc.status || written != tc.written {
+				t.Errorf("expected %v %d %d; got %v %d %d", tc.result, tc.status, tc.written, result, status, written)
+			}
+		}
+
+		Handler(tc.handler, "supersecret",
+			OptionSkipMethods("GET"),
+			OptionMissingSignatureStatus(http.StatusBadRequest),
+			OptionResponseObserver(observer),
+		).ServeHTTP(rec, req)
+
+		if called != tc.called {
+			t.Errorf("expected observer called %v; got %v", tc.called, called)
+		}
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (hr hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestResponseObserverPassThrough(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		w        http.ResponseWriter
+		hijackOK bool
+		status   int
+	}{
+		{httptest.NewRecorder(), false, http.StatusOK},
+		{hijackRecorder{httptest.NewRecorder()}, true, 0},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, "supersecret"))
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(http.Flusher); !ok {
+				t.Error("expected http.Flusher")
+			}
+
+			_, _, err := w.(http.Hijacker).Hijack()
+			if tc.hijackOK && err != nil {
+				t.Errorf("expected hijack to succeed; got '%v'", err)
+			}
+
+			if !tc.hijackOK && !errors.Is(err, http.ErrNotSupported) {
+				t.Errorf("expected '%v'; got '%v'", http.ErrNotSupported, err)
+			}
+
+			if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != tc.w {
+				t.Error("expected Unwrap to return the underlying writer")
+			}
+		})
+
+		observer := func(result Result, r *http.Request, status int, written int64) {
+			if status != tc.status {
+				t.Errorf("expected status %d; got %d", tc.status, status)
+			}
+		}
+
+		Handler(x, "supersecret", OptionResponseObserver(observer)).ServeHTTP(tc.w, req)
+	}
+
+	rec := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: rec}
+	rw.Flush()
+	if !rec.Flushed {
+		t.Error("expected flush to pass through")
+	}
+}
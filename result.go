@@ -0,0 +1,116 @@
+package hmacsig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Result classifies the outcome of verifying a request, as passed to the
+// function configured by OptionObserver
+type Result int
+
+const (
+	// ResultOK indicates the signature was verified
+	ResultOK Result = iota
+
+	// ResultMissing indicates the signature was missing from the request
+	ResultMissing
+
+	// ResultFailed indicates the signature was malformed or did not match, or
+	// the request was rejected by replay protection, the IP allowlist, its
+	// content type, its lack of a Content-Length or no route matching its path
+	ResultFailed
+
+	// ResultError indicates verification could not be performed, for
+	// instance because the body could not be read or the secret could not be
+	// resolved
+	ResultError
+)
+
+func (res Result) String() string {
+	switch res {
+	case ResultOK:
+		return "ok"
+	case ResultMissing:
+		return "missing"
+	case ResultFailed:
+		return "failed"
+	case ResultError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// resultFor classifies a failure reason as a Result
+func resultFor(reason error) Result {
+	switch {
+	case errors.Is(reason, ErrMissingSignature):
+		return ResultMissing
+	case errors.Is(reason, ErrMalformedSignature), errors.Is(reason, ErrSignatureMismatch),
+		errors.Is(reason, ErrInvalidTimestamp), errors.Is(reason, ErrStaleTimestamp),
+		errors.Is(reason, ErrMissingDeliveryID), errors.Is(reason, ErrReplayed),
+		errors.Is(reason, ErrForbiddenIP), errors.Is(reason, ErrUnsupportedMediaType),
+		errors.Is(reason, ErrLengthRequired), errors.Is(reason, ErrNoRoute):
+		return ResultFailed
+	}
+
+	return ResultError
+}
+
+// OptionObserver configures a function called once per verified request with
+// the Result of verification, for instance to increment metrics counters. It
+// is called before the response is written. By default no observer is called.
+func OptionObserver(fn func(result Result, r *http.Request)) Option {
+	return func(mux *hmacSig) {
+		mux.observer = fn
+	}
+}
+
+// OptionTimedObserver configures a function called as by OptionObserver, and
+// alongside it, additionally passed the time from the start of verification
+// to the decision. The wrapped handler is excluded, but reading the body is
+// included, which for slow clients can dominate.
+func OptionTimedObserver(fn func(result Result, r *http.Request, d time.Duration)) Option {
+	return func(mux *hmacSig) {
+		mux.timedObserver = fn
+	}
+}
+
+// OptionOnVerified configures a function called once per successfully verified
+// request, for business logic such as recording the delivery ID for
+// idempotency, as opposed to the metrics OptionObserver is intended for. It is
+// called after replay protection and with the body restored, immediately
+// before the wrapped handler, and never for rejected or skipped requests.
+func OptionOnVerified(fn func(r *http.Request)) Option {
+	return func(mux *hmacSig) {
+		mux.onVerified = fn
+	}
+}
+
+// withStart returns r with the start of verification recorded in its context
+// if it is to be observed by the timed observer
+func (xh *hmacSig) withStart(r *http.Request) *http.Request {
+	if xh.timedObserver == nil {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), startContextKey, time.Now()))
+}
+
+func (xh *hmacSig) observe(w http.ResponseWriter, result Result, r *http.Request) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.observe(result, r)
+	}
+
+	if xh.observer != nil {
+		xh.observer(result, r)
+	}
+
+	if xh.timedObserver != nil {
+		start, _ := r.Context().Value(startContextKey).(time.Time)
+		xh.timedObserver(result, r, time.Since(start))
+	}
+}
@@ -0,0 +1,170 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObserver(t *testing.T) {
+	tt := []struct {
+		reqHeader  string
+		secretFunc func(r *http.Request) (string, error)
+		result     Result
+	}{
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", nil, ResultOK},
+		{"", nil, ResultMissing},
+		{"garbage", nil, ResultFailed},
+		{"sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", nil, ResultFailed},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", func(r *http.Request) (string, error) {
+			return "", errors.New("unknown tenant")
+		}, ResultError},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		var results []Result
+		options := []Option{OptionObserver(func(result Result, r *http.Request) {
+			results = append(results, result)
+		})}
+		if tc.secretFunc != nil {
+			options = append(options, OptionSecretFunc(tc.secretFunc))
+		}
+
+		xhs := Handler(x, "supersecret", options...)
+		xhs.ServeHTTP(rec, req)
+
+		if len(results) != 1 || results[0] != tc.result {
+			t.Errorf("expected results [%v]; got %v", tc.result, results)
+		}
+	}
+}
+
+// slowReader delays each read, as a slow client would
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (sr slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.r.Read(p)
+}
+
+func TestTimedObserver(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		result    Result
+	}{
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", ResultOK},
+		{"sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", ResultFailed},
+	}
+
+	for _, tc := range tt {
+		body := slowReader{bytes.NewReader([]byte("This is the body of the request")), 20 * time.Millisecond}
+		req, _ := http.NewRequest("POST", "localhost", body)
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		handled := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = true
+		})
+
+		calls := 0
+		xhs := Handler(x, "supersecret", OptionTimedObserver(func(result Result, r *http.Request, d time.Duration) {
+			calls++
+			if result != tc.result {
+				t.Errorf("expected result %v; got %v", tc.result, result)
+			}
+
+			if d < 20*time.Millisecond || d > time.Minute {
+				t.Errorf("expected duration including the body read; got %v", d)
+			}
+
+			if handled {
+				t.Errorf("expected observer to be called before the handler")
+			}
+		}))
+		xhs.ServeHTTP(rec, req)
+
+		if calls != 1 {
+			t.Errorf("expected 1 call; got %d", calls)
+		}
+	}
+}
+
+func TestOnVerified(t *testing.T) {
+	tt := []struct {
+		reqHeader string
+		method    string
+		called    bool
+	}{
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "POST", true},
+		{"sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "POST", false},
+		{"sha1=zz", "POST", false},
+		{"", "POST", false},
+		{"", "GET", false},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.reqHeader)
+		rec := httptest.NewRecorder()
+
+		handled := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = true
+		})
+
+		called := false
+		xhs := Handler(x, "supersecret", OptionSkipMethods("GET"), OptionOnVerified(func(r *http.Request) {
+			called = true
+			if handled {
+				t.Errorf("expected callback to be called before the handler")
+			}
+
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != "This is the body of the request" {
+				t.Errorf("expected restored body; got '%s'", b)
+			}
+
+			r.Body, _ = r.GetBody()
+		}))
+		xhs.ServeHTTP(rec, req)
+
+		if called != tc.called {
+			t.Errorf("expected called %v for %s '%s'; got %v", tc.called, tc.method, tc.reqHeader, called)
+		}
+	}
+}
+
+func TestResultString(t *testing.T) {
+	tt := []struct {
+		result Result
+		str    string
+	}{
+		{ResultOK, "ok"},
+		{ResultMissing, "missing"},
+		{ResultFailed, "failed"},
+		{ResultError, "error"},
+		{Result(-1), "unknown"},
+	}
+
+	for _, tc := range tt {
+		if s := tc.result.String(); s != tc.str {
+			t.Errorf("expected '%v'; got '%v'", tc.str, s)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package hmacsig
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MsgNoRoute is the message returned in the body when no route configured by
+// OptionRoutes matched the request path
+const MsgNoRoute = "No HMAC configuration for path"
+
+// ErrNoRoute is the failure reason when no route configured by OptionRoutes
+// matched the request path
+var ErrNoRoute = errors.New("hmacsig: no route for path")
+
+// Route pairs a request path with the secret and options used to verify
+// requests to it
+type Route struct {
+	// Path is matched against r.URL.Path. As with http.ServeMux, a Path ending
+	// in a slash matches every path it prefixes, and otherwise only itself.
+	Path string
+
+	// Secret replaces the secret passed to Handler for the route, along with
+	// any inherited OptionSecrets, OptionSecretFunc, OptionSecretStore or
+	// similar. A route may configure those itself through Options.
+	Secret string
+
+	// Options are applied after those passed to Handler, e.g.
+	// OptionProvider(ProviderStripe)
+	Options []Option
+}
+
+type route struct {
+	path string
+	sig  *hmacSig
+}
+
+// OptionRoutes configures the middleware to select the secret, header and
+// validator for each request by the longest Route matching its path,
+// allowing a single middleware to serve several providers mounted at
+// different paths.
+//
+// Requests matching no route are passed to the no route handler, unless they
+// are skipped by OptionSkipPaths, OptionSkipMethods or OptionSkip, which
+// routes inherit along with the other options.
+func OptionRoutes(routes ...Route) Option {
+	return func(mux *hmacSig) {
+		mux.routeConfigs = routes
+	}
+}
+
+// OptionNoRouteHandler configures the http.Handler called when no route
+// configured by OptionRoutes matches the request path
+func OptionNoRouteHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.noRouteHandler = handler
+	}
+}
+
+// DefaultNoRouteHandler is the default response to no route matching the
+// request path
+func DefaultNoRouteHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgNoRoute, http.StatusNotFound)
+}
+
+// JSONNoRouteHandler responds to no route matching the request path with a
+// JSON body of the form {"error":"..."}
+func JSONNoRouteHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgNoRoute, http.StatusNotFound)
+}
+
+// buildRoutes builds a handler per configured route from options, the options
+// the middleware itself was built with
+func (xh *hmacSig) buildRoutes(options []Option) {
+	xh.routes = make([]route, len(xh.routeConfigs))
+	for i, rc := range xh.routeConfigs {
+		opts := append(append(options[:len(options):len(options)], optionNoRoutes, optionRouteSecret(rc.Secret), xh.optionSharedReads), rc.Options...)
+		xh.routes[i] = route{rc.Path, newHMACSig(xh.h, rc.Secret, opts...)}
+	}
+}
+
+// optionNoRoutes clears the routes inherited by a route's own handler
+func optionNoRoutes(mux *hmacSig) {
+	mux.routeConfigs = nil
+}
+
+// optionRouteSecret replaces the secret sources inherited by a route's own
+// handler with the route's secret
+func optionRouteSecret(secret string) Option {
+	return func(mux *hmacSig) {
+		mux.secrets = []string{secret}
+		mux.secretNames = nil
+		mux.secretFunc = nil
+		mux.secretStore = nil
+	}
+}
+
+// optionSharedReads shares the slots configured by OptionMaxConcurrentReads
+// on xh with a route's own handler, so that the limit spans every route
+func (xh *hmacSig) optionSharedReads(mux *hmacSig) {
+	mux.readSlots = xh.readSlots
+}
+
+// route returns the handler for the longest route matching the path of r, or
+// nil if none match
+func (xh *hmacSig) route(r *http.Request) *hmacSig {
+	var match *route
+	for i, rt := range xh.routes {
+		if !pathMatches(rt.path, r.URL.Path) {
+			continue
+		}
+
+		if match == nil || len(rt.path) > len(match.path) {
+			match = &xh.routes[i]
+		}
+	}
+
+	if match == nil {
+		return nil
+	}
+
+	return match.sig
+}
+
+func pathMatches(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+
+	return pattern == path
+}
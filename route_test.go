@@ -0,0 +1,162 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	routes := OptionRoutes(
+		Route{Path: "/github", Secret: "supersecret"},
+		Route{Path: "/hooks/", Secret: "ThisKeyIsAGreatSecretYouShouldNotUseIt"},
+		Route{Path: "/hooks/sha256/", Secret: "EvenDifferentKey", Options: []Option{OptionDefaultsSHA256}},
+	)
+
+	tt := []struct {
+		path   string
+		header string
+		sig    string
+		body   string
+		status int
+	}{
+		{"/github", GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusOK},
+		{"/github", GithubSignatureHeader, "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "This is a more different body", http.StatusForbidden},
+		{"/github", GithubSignatureHeader, "", "This is the body of the request", http.StatusForbidden},
+		{"/github/sub", GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusNotFound},
+		{"/hooks/other", GithubSignatureHeader, "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "This is a more different body", http.StatusOK},
+		{"/hooks/sha256/x", GithubSignatureHeader256, "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "This body is super", http.StatusOK},
+		{"/hooks/sha256/x", GithubSignatureHeader, "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", "This is a more different body", http.StatusForbidden},
+		{"/stripe", GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "This is the body of the request", http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "http://localhost"+tc.path, bytes.NewReader([]byte(tc.body)))
+		req.Header.Set(tc.header, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler(x, "", routes)
+		xhs.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %s; got %d", tc.status, tc.path, rec.Code)
+		}
+	}
+}
+
+func TestNoRouteHandler(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://localhost/unknown", bytes.NewReader([]byte{}))
+	rec := httptest.NewRecorder()
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("should not be executed")
+	})
+
+	var got error
+	noRoute := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ErrorFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	xhs := Handler(x, "", OptionRoutes(Route{Path: "/github", Secret: "supersecret"}), OptionNoRouteHandler(noRoute))
+	xhs.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("expected status %d; got %d", http.StatusTeapot, rec.Code)
+	}
+
+	if !errors.Is(got, ErrNoRoute) {
+		t.Errorf("expected reason '%v'; got '%v'", ErrNoRoute, got)
+	}
+}
+
+func TestRoutesConfig(t *testing.T) {
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	if _, err := NewHandler(x, "", OptionRoutes(Route{Path: "/github", Secret: "supersecret"})); err != nil {
+		t.Errorf("expected no error; got '%v'", err)
+	}
+
+	_, err := NewHandler(x, "", OptionRoutes(Route{Path: "/github", Secret: "supersecret"}, Route{Path: "/gitlab"}))
+	if !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("expected '%v'; got '%v'", ErrEmptySecret, err)
+	}
+}
+
+func TestRoutesSecret(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name    string
+		options []Option
+	}{
+		{"secrets", []Option{OptionSecrets("parent")}},
+		{"named secrets", []Option{OptionNamedSecrets(NamedSecret{"current", "parent"})}},
+		{"secret bytes", []Option{OptionSecretBytes([]byte("parent"))}},
+		{"secret func", []Option{OptionSecretFunc(func(r *http.Request) (string, error) { return "parent", nil })}},
+		{"secret store", []Option{OptionSecretStore(NewSecretStore("parent"))}},
+	}
+
+	for _, tc := range tt {
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		xhs := Handler(x, "parent", append(tc.options, OptionRoutes(Route{Path: "/a", Secret: "routesecret"}))...)
+
+		for secret, status := range map[string]int{"routesecret": http.StatusOK, "parent": http.StatusForbidden} {
+			req, _ := http.NewRequest("POST", "http://localhost/a", bytes.NewReader(body))
+			req.Header.Set(GithubSignatureHeader, SignSHA1(body, secret))
+			rec := httptest.NewRecorder()
+			xhs.ServeHTTP(rec, req)
+
+			if rec.Code != status {
+				t.Errorf("%s: expected status %d signed with %q; got %d", tc.name, status, secret, rec.Code)
+			}
+		}
+	}
+}
+
+func TestRoutesSkip(t *testing.T) {
+	tt := []struct {
+		method string
+		path   string
+		sig    string
+		status int
+		skip   bool
+	}{
+		{"GET", "/healthz", "", http.StatusOK, true},
+		{"POST", "/healthz", "", http.StatusOK, true},
+		{"GET", "/unknown", "", http.StatusOK, true},
+		{"POST", "/unknown", "", http.StatusNotFound, false},
+		{"POST", "/github", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", http.StatusOK, false},
+		{"POST", "/github", "", http.StatusForbidden, false},
+		{"GET", "/github", "", http.StatusOK, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, "http://localhost"+tc.path, bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := FromContext(r.Context())
+			if skipped := v.Disposition == DispositionSkipped; skipped != tc.skip {
+				t.Errorf("expected skipped %v for %s %s; got %v", tc.skip, tc.method, tc.path, v.Disposition)
+			}
+		})
+
+		xhs := Handler(x, "",
+			OptionRoutes(Route{Path: "/github", Secret: "supersecret"}),
+			OptionSkipPaths("/healthz"),
+			OptionSkipMethods("GET"))
+		xhs.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %s %s; got %d", tc.status, tc.method, tc.path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package hmacsig
+
+import (
+	"sync/atomic"
+)
+
+// SecretStore holds the set of secrets a signature is accepted for, which may
+// be replaced at runtime, for instance by a file watcher, while requests are
+// being served. It is safe for concurrent use.
+type SecretStore struct {
+	v atomic.Value
+}
+
+// NewSecretStore returns a SecretStore holding the given secrets. If they are
+// rejected by Set the store holds none, which NewHandler reports and which
+// fails every request until Set succeeds.
+func NewSecretStore(secrets ...string) *SecretStore {
+	s := &SecretStore{}
+	s.Set(secrets...)
+
+	return s
+}
+
+// Set atomically replaces the secrets held. Requests being verified complete
+// against the secrets held when verification began; later requests are
+// verified against the new secrets. Passing several secrets allows a new and
+// an old secret to be accepted during rotation.
+//
+// If no secrets are passed, or any is empty, ErrEmptySecret is returned and
+// the secrets held are left unchanged.
+func (s *SecretStore) Set(secrets ...string) error {
+	if len(secrets) == 0 {
+		return ErrEmptySecret
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			return ErrEmptySecret
+		}
+	}
+
+	s.v.Store(append([]string(nil), secrets...))
+
+	return nil
+}
+
+// Secrets returns the secrets held
+func (s *SecretStore) Secrets() []string {
+	secrets, _ := s.v.Load().([]string)
+
+	return secrets
+}
+
+// OptionSecretStore configures the secrets a signature is accepted for to be
+// read from s on each request, replacing the secret passed to Handler and
+// those configured by OptionSecrets. OptionSecretFunc takes precedence over
+// it.
+func OptionSecretStore(s *SecretStore) Option {
+	return func(mux *hmacSig) {
+		mux.secretStore = s
+	}
+}
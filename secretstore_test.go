@@ -0,0 +1,142 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestSecretStore(t *testing.T) {
+	body := []byte("This is the body of the request")
+	store := NewSecretStore("old")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	xhs := Handler(x, "", OptionSecretStore(store))
+
+	serve := func(secret string) int {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set(GithubSignatureHeader, SignSHA1(body, secret))
+		rec := httptest.NewRecorder()
+		xhs.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	if code := serve("old"); code != http.StatusOK {
+		t.Errorf("expected status OK for old secret; got %d", code)
+	}
+
+	store.Set("new", "old")
+	if code := serve("new"); code != http.StatusOK {
+		t.Errorf("expected status OK for new secret during rotation; got %d", code)
+	}
+	if code := serve("old"); code != http.StatusOK {
+		t.Errorf("expected status OK for old secret during rotation; got %d", code)
+	}
+
+	store.Set("new")
+	if code := serve("old"); code != http.StatusForbidden {
+		t.Errorf("expected status Forbidden for retired secret; got %d", code)
+	}
+
+	if _, err := NewHandler(x, "", OptionSecretStore(store)); err != nil {
+		t.Errorf("expected no configuration error with a secret store; got %v", err)
+	}
+}
+
+func TestSecretStoreEmpty(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	store := NewSecretStore("")
+	if _, err := NewHandler(x, "", OptionSecretStore(store)); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("expected '%v' for an empty store; got '%v'", ErrEmptySecret, err)
+	}
+
+	var got error
+	secretError := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ErrorFromContext(r.Context())
+		DefaultSecretErrorHandler(w, r)
+	})
+
+	req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+	req.Header.Set(GithubSignatureHeader, SignSHA1(body, ""))
+	rec := httptest.NewRecorder()
+	Handler(x, "", OptionSecretStore(store), OptionSecretErrorHandler(secretError)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || !errors.Is(got, ErrSecretLookup) {
+		t.Errorf("expected status %d and '%v' for an empty store; got %d '%v'", http.StatusInternalServerError, ErrSecretLookup, rec.Code, got)
+	}
+
+	store = NewSecretStore("old")
+	for _, secrets := range [][]string{nil, {""}, {"new", ""}} {
+		if err := store.Set(secrets...); !errors.Is(err, ErrEmptySecret) {
+			t.Errorf("expected '%v' setting %q; got '%v'", ErrEmptySecret, secrets, err)
+		}
+	}
+
+	if s := store.Secrets(); len(s) != 1 || s[0] != "old" {
+		t.Errorf("expected rejected secrets to leave [old] held; got %q", s)
+	}
+}
+
+func TestSecretStoreConcurrent(t *testing.T) {
+	body := []byte("This is the body of the request")
+	store := NewSecretStore("a", "b")
+
+	x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	xhs := Handler(x, "", OptionSecretStore(store))
+
+	done := make(chan struct{})
+	var toggler sync.WaitGroup
+	toggler.Add(1)
+	go func() {
+		defer toggler.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if i%2 == 0 {
+				store.Set("b", "a")
+			} else {
+				store.Set("a", "b")
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+				req.Header.Set(GithubSignatureHeader, SignSHA1(body, "a"))
+				rec := httptest.NewRecorder()
+				xhs.ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusOK {
+					t.Errorf("expected status OK; got %d", rec.Code)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	toggler.Wait()
+}
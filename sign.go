@@ -0,0 +1,64 @@
+package hmacsig
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+)
+
+// Sign computes the HMAC of body with the given secret and hash, returning
+// the hex encoded digest preceded by prefix, e.g. "sha256=".
+//
+// The result is the exact header value the matching SignatureValidator
+// expects.
+func Sign(body []byte, secret string, h func() hash.Hash, prefix string) string {
+	return SignKey(body, []byte(secret), h, prefix)
+}
+
+// SignKey is Sign for a secret key supplied as raw bytes, as expected by the
+// KeyValidator returned by NewKeyValidator
+func SignKey(body, key []byte, h func() hash.Hash, prefix string) string {
+	return prefix + hex.EncodeToString(labeledDigest(nil, body, key, h))
+}
+
+// digest computes the raw HMAC of body with the given secret and hash
+func digest(body []byte, secret string, h func() hash.Hash) []byte {
+	return labeledDigest(nil, body, []byte(secret), h)
+}
+
+// labeledDigest computes the raw HMAC of label followed by body with the given
+// key and hash
+func labeledDigest(label, body, key []byte, h func() hash.Hash) []byte {
+	mac := hmac.New(h, key)
+	mac.Write(label)
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
+
+// SignSHA1 computes the SHA-1 HMAC signature of body as expected by
+// SHA1Validator
+func SignSHA1(body []byte, secret string) string {
+	return Sign(body, secret, sha1.New, "sha1=")
+}
+
+// SignSHA256 computes the SHA-256 HMAC signature of body as expected by
+// SHA256Validator
+func SignSHA256(body []byte, secret string) string {
+	return Sign(body, secret, sha256.New, "sha256=")
+}
+
+// SignSHA384 computes the SHA-384 HMAC signature of body as expected by
+// SHA384Validator
+func SignSHA384(body []byte, secret string) string {
+	return Sign(body, secret, sha512.New384, "sha384=")
+}
+
+// SignSHA224 computes the SHA-224 HMAC signature of body as expected by
+// SHA224Validator
+func SignSHA224(body []byte, secret string) string {
+	return Sign(body, secret, sha256.New224, "sha224=")
+}
@@ -0,0 +1,49 @@
+package hmacsig
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	tt := []struct {
+		body   string
+		secret string
+		hash   func() hash.Hash
+		prefix string
+		sig    string
+	}{
+		{"This is the body of the request", "supersecret", sha1.New, "sha1=", "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"},
+		{"This is a more different body", "ThisKeyIsAGreatSecretYouShouldNotUseIt", sha1.New, "sha1=", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52"},
+		{"This body is super", "EvenDifferentKey", sha256.New, "sha256=", "sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"},
+		{"This body is super", "EvenDifferentKey", sha256.New, "", "814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"},
+		{"This body is super", "EvenDifferentKey", sha512.New384, "sha384=", "sha384=72683405aa30d7ca2285eef0ff72f1f0eb781fdcb9783ab42abc490c04b4882a21333b58d2775600882a8cfbff84b121"},
+		{"This body is super", "EvenDifferentKey", sha256.New224, "sha224=", "sha224=24ab5cfb29b127ec7be02744cb0819f117c4661e9db71a43e962d0ac"},
+	}
+
+	for _, tc := range tt {
+		sig := Sign([]byte(tc.body), tc.secret, tc.hash, tc.prefix)
+		if sig != tc.sig {
+			t.Errorf("expected signature '%v'; got '%v'", tc.sig, sig)
+		}
+	}
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	if !SHA1Validator(body, SignSHA1(body, "supersecret"), "supersecret") {
+		t.Error("expected SignSHA1 to validate with SHA1Validator")
+	}
+
+	if !SHA256Validator(body, SignSHA256(body, "supersecret"), "supersecret") {
+		t.Error("expected SignSHA256 to validate with SHA256Validator")
+	}
+
+	if SHA256Validator(body, SignSHA256(body, "supersecret"), "othersecret") {
+		t.Error("expected SignSHA256 not to validate with a different secret")
+	}
+}
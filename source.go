@@ -0,0 +1,63 @@
+package hmacsig
+
+// OptionQueryParam configures the signature to be read from the named query
+// parameter, e.g. "signature", rather than the header. A missing parameter is
+// treated as a missing signature.
+//
+// To read both, combine it with OptionQueryParamFallback, which reads the
+// query parameter and only falls back to the header when the parameter is
+// absent, or OptionPreferHeader, which does the inverse. Either way only one
+// source is validated, and the signature is missing only if both are absent.
+//
+// With OptionHeaderValidators and the like, every header's validator is
+// applied to the query parameter value.
+func OptionQueryParam(name string) Option {
+	return func(mux *hmacSig) {
+		mux.queryParam = name
+	}
+}
+
+// OptionQueryParamFallback configures the header to be read when the query
+// parameter configured by OptionQueryParam is absent
+func OptionQueryParamFallback(mux *hmacSig) {
+	mux.queryFallback = true
+	mux.preferHeader = false
+}
+
+// OptionPreferHeader configures the header to be read in preference to the
+// query parameter configured by OptionQueryParam, which is only read when the
+// header is absent. It inverts OptionQueryParamFallback.
+func OptionPreferHeader(mux *hmacSig) {
+	mux.queryFallback = true
+	mux.preferHeader = true
+}
+
+// OptionCommaSeparated configures the signature to be read as a comma
+// separated list of signatures, surrounding whitespace trimmed, passing if any
+// of them validates. By default the signature is validated as a whole.
+func OptionCommaSeparated(mux *hmacSig) {
+	mux.commaSeparated = true
+}
+
+// OptionMultipleHeaders configures every instance of the signature header to
+// be read, for clients repeating the header rather than comma joining its
+// values, passing if any of them validates. By default only the first
+// instance is read. Combined with OptionCommaSeparated, each instance is read
+// as a comma separated list.
+func OptionMultipleHeaders(mux *hmacSig) {
+	mux.multipleHeaders = true
+}
+
+// OptionAuthorizationScheme configures the signature to be read from the
+// Authorization header in the given scheme, matched case-insensitively, e.g.
+// "Authorization: HMAC-SHA256 <signature>" for the scheme "HMAC-SHA256". A
+// missing header or one in any other scheme is treated as a missing
+// signature.
+//
+// This replaces the header configured by OptionHeader.
+func OptionAuthorizationScheme(scheme string) Option {
+	return func(mux *hmacSig) {
+		mux.header = "Authorization"
+		mux.authScheme = scheme
+	}
+}
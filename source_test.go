@@ -0,0 +1,210 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestQueryParam(t *testing.T) {
+	valid := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+	invalid := "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52"
+
+	tt := []struct {
+		name    string
+		query   string
+		header  string
+		options []Option
+		status  int
+		missing bool
+	}{
+		{"query valid", valid, "", nil, http.StatusOK, false},
+		{"query invalid", invalid, "", nil, http.StatusForbidden, false},
+		{"query missing", "", "", nil, http.StatusForbidden, true},
+		{"query missing, header ignored", "", valid, nil, http.StatusForbidden, true},
+		{"query invalid, header ignored", invalid, valid, nil, http.StatusForbidden, false},
+		{"fallback to header", "", valid, []Option{OptionQueryParamFallback}, http.StatusOK, false},
+		{"fallback unused", invalid, valid, []Option{OptionQueryParamFallback}, http.StatusForbidden, false},
+		{"fallback missing", "", "", []Option{OptionQueryParamFallback}, http.StatusForbidden, true},
+		{"prefer header, header only", "", valid, []Option{OptionPreferHeader}, http.StatusOK, false},
+		{"prefer header, query only", valid, "", []Option{OptionPreferHeader}, http.StatusOK, false},
+		{"prefer header, both", invalid, valid, []Option{OptionPreferHeader}, http.StatusOK, false},
+		{"prefer header, both, header invalid", valid, invalid, []Option{OptionPreferHeader}, http.StatusForbidden, false},
+		{"prefer header, neither", "", "", []Option{OptionPreferHeader}, http.StatusForbidden, true},
+		{"fallback inverts prefer header", invalid, valid, []Option{OptionPreferHeader, OptionQueryParamFallback}, http.StatusForbidden, false},
+	}
+
+	for _, tc := range tt {
+		target := "http://localhost/hook"
+		if tc.query != "" {
+			target += "?signature=" + url.QueryEscape(tc.query)
+		}
+
+		req, _ := http.NewRequest("POST", target, bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.header)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		var missing bool
+		missingHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			missing = true
+			DefaultMissingSignatureHandler(w, r)
+		})
+
+		options := append([]Option{OptionQueryParam("signature"), OptionMissingSignatureHandler(missingHandler)}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if missing != tc.missing {
+			t.Errorf("%s: expected missing %v; got %v", tc.name, tc.missing, missing)
+		}
+	}
+}
+
+func TestCommaSeparated(t *testing.T) {
+	valid := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+	invalid := "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52"
+
+	tt := []struct {
+		sig     string
+		options []Option
+		status  int
+	}{
+		{valid, nil, http.StatusOK},
+		{invalid + "," + valid, nil, http.StatusBadRequest},
+		{valid, []Option{OptionCommaSeparated}, http.StatusOK},
+		{invalid + "," + valid, []Option{OptionCommaSeparated}, http.StatusOK},
+		{invalid + " , " + valid + " ", []Option{OptionCommaSeparated}, http.StatusOK},
+		{"garbage, " + valid, []Option{OptionCommaSeparated}, http.StatusOK},
+		{invalid + "," + invalid, []Option{OptionCommaSeparated}, http.StatusForbidden},
+		{"garbage, " + invalid, []Option{OptionCommaSeparated}, http.StatusForbidden},
+		{"garbage,also garbage", []Option{OptionCommaSeparated}, http.StatusBadRequest},
+		{" , ", []Option{OptionCommaSeparated}, http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.sig, rec.Code)
+		}
+	}
+}
+
+func TestMultipleHeaders(t *testing.T) {
+	valid := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+	invalid := "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52"
+
+	tt := []struct {
+		sigs    []string
+		options []Option
+		status  int
+	}{
+		{[]string{invalid, valid}, nil, http.StatusForbidden},
+		{[]string{valid, invalid}, nil, http.StatusOK},
+		{[]string{invalid, valid}, []Option{OptionMultipleHeaders}, http.StatusOK},
+		{[]string{"garbage", " " + valid + " "}, []Option{OptionMultipleHeaders}, http.StatusOK},
+		{[]string{invalid, "garbage"}, []Option{OptionMultipleHeaders}, http.StatusForbidden},
+		{[]string{invalid, invalid}, []Option{OptionMultipleHeaders}, http.StatusForbidden},
+		{[]string{invalid, "garbage, " + valid}, []Option{OptionMultipleHeaders}, http.StatusForbidden},
+		{[]string{invalid, "garbage, " + valid}, []Option{OptionMultipleHeaders, OptionCommaSeparated}, http.StatusOK},
+		{[]string{invalid, valid}, []Option{OptionMultipleHeaders, OptionQueryParam("sig")}, http.StatusForbidden},
+		{[]string{invalid, valid}, []Option{OptionMultipleHeaders, OptionQueryParam("sig"), OptionQueryParamFallback}, http.StatusOK},
+		{[]string{invalid, valid}, []Option{OptionMultipleHeaders, OptionVerifyOnRead}, http.StatusOK},
+		{[]string{"", valid}, nil, http.StatusForbidden},
+		{[]string{"", valid}, []Option{OptionMultipleHeaders}, http.StatusOK},
+		{[]string{" ", valid}, []Option{OptionMultipleHeaders, OptionQueryParam("sig"), OptionPreferHeader}, http.StatusOK},
+		{[]string{"", " "}, []Option{OptionMultipleHeaders, OptionMissingSignatureStatus(http.StatusUnauthorized)}, http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte("This is the body of the request")))
+		for _, sig := range tc.sigs {
+			req.Header.Add(GithubSignatureHeader, sig)
+		}
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for %q; got %d", tc.status, tc.sigs, rec.Code)
+		}
+	}
+}
+
+func TestAuthorizationScheme(t *testing.T) {
+	body := []byte("This body is super")
+	sig := "814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23"
+
+	tt := []struct {
+		auth    string
+		status  int
+		missing bool
+	}{
+		{"HMAC-SHA256 " + sig, http.StatusOK, false},
+		{"hmac-sha256 " + sig, http.StatusOK, false},
+		{"HMAC-SHA256  " + sig + " ", http.StatusOK, false},
+		{"HMAC-SHA256 " + Sign(body, "WrongKey", sha256.New, ""), http.StatusForbidden, false},
+		{"Bearer " + sig, http.StatusForbidden, true},
+		{sig, http.StatusForbidden, true},
+		{"HMAC-SHA256", http.StatusForbidden, true},
+		{"", http.StatusForbidden, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(body))
+		req.Header.Set("Authorization", tc.auth)
+		rec := httptest.NewRecorder()
+
+		var verified string
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := FromContext(r.Context())
+			verified = v.Header
+		})
+
+		var missing bool
+		missingHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			missing = true
+			DefaultMissingSignatureHandler(w, r)
+		})
+
+		Handler(x, "EvenDifferentKey",
+			OptionAuthorizationScheme("HMAC-SHA256"),
+			OptionSignatureValidator(SHA256RawValidator),
+			OptionMissingSignatureHandler(missingHandler),
+		).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("expected status %d for '%s'; got %d", tc.status, tc.auth, rec.Code)
+		}
+
+		if missing != tc.missing {
+			t.Errorf("expected missing %v for '%s'; got %v", tc.missing, tc.auth, missing)
+		}
+
+		if tc.status == http.StatusOK && verified != "Authorization" {
+			t.Errorf("expected verified header 'Authorization'; got '%s'", verified)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// OptionSpillToDisk configures a body size threshold in bytes above which the
+// body is streamed through the HMAC computation while being written to a
+// temporary file in dir, rather than buffered in memory. The wrapped handler
+// then reads the body from that file, which is removed once the body is
+// closed or the handler returns. As the body can only be read once, GetBody is
+// nil for spilled requests. If dir is empty os.TempDir is used.
+//
+// Streaming requires the hash to be known, so this applies only to the
+// algorithms configured by Handler, Handler256, OptionDefaultsSHA256 and
+// OptionSignaturePrefix; with other validators bodies are always buffered in
+// memory.
+func OptionSpillToDisk(threshold int64, dir string) Option {
+	return func(mux *hmacSig) {
+		mux.spillThreshold = threshold
+		mux.spillDir = dir
+	}
+}
+
+// canSpill reports whether spilling to disk is configured and possible
+func (xh *hmacSig) canSpill() bool {
+	return xh.spillThreshold > 0 && xh.canStream() && !xh.reportOnly
+}
+
+// spills reports whether the partially read body b exceeded the spill
+// threshold
+func (xh *hmacSig) spills(b []byte) bool {
+	return xh.canSpill() && int64(len(b)) > xh.spillThreshold
+}
+
+// serveSpilled verifies the request given the already read head of its body,
+// streaming the remainder to a temporary file
+func (xh *hmacSig) serveSpilled(w http.ResponseWriter, r *http.Request, head []byte) {
+	sv, err := xh.newStreamVerifier(r)
+	if err != nil {
+		xh.fail(w, r, err)
+		return
+	}
+
+	f, err := os.CreateTemp(xh.spillDir, "hmacsig-*")
+	if err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
+		return
+	}
+
+	body := &spillBody{f: f}
+	defer body.Close()
+
+	var n int64
+	err = xh.timedRead(r, func(rest io.Reader) (err error) {
+		if xh.maxBodyBytes > 0 {
+			rest = io.LimitReader(rest, xh.maxBodyBytes+1-int64(len(head)))
+		}
+		n, err = io.Copy(io.MultiWriter(f, sv), io.MultiReader(bytes.NewReader(head), rest))
+		return err
+	})
+	if errors.Is(err, errCanceled) {
+		return
+	}
+	if errors.Is(err, ErrReadTimeout) {
+		xh.fail(w, r, err)
+		return
+	}
+	if err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
+		return
+	}
+
+	if xh.maxBodyBytes > 0 && n > xh.maxBodyBytes {
+		xh.fail(w, r, ErrBodyTooLarge)
+		return
+	}
+
+	v, err := sv.verify()
+	r = withBodySize(r, n)
+	if err != nil {
+		xh.setStreamExpected(w, sv, err)
+		xh.fail(w, r, err)
+		return
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		xh.fail(w, r, fmt.Errorf("%w: %v", ErrReadBody, err))
+		return
+	}
+
+	xh.pass(w, r, v, body, nil)
+}
+
+// spillBody is a request body read from a temporary file, which is removed
+// when the body is closed
+type spillBody struct {
+	f    *os.File
+	once sync.Once
+}
+
+func (sb *spillBody) Read(p []byte) (int, error) {
+	return sb.f.Read(p)
+}
+
+// Seek implements io.Seeker, allowing the body to be reread
+func (sb *spillBody) Seek(offset int64, whence int) (int64, error) {
+	return sb.f.Seek(offset, whence)
+}
+
+// Close closes and removes the underlying temporary file. It is safe to call
+// more than once.
+func (sb *spillBody) Close() error {
+	var err error
+	sb.once.Do(func() {
+		err = sb.f.Close()
+		if rerr := os.Remove(sb.f.Name()); err == nil {
+			err = rerr
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,77 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestSpillToDisk(t *testing.T) {
+	small := []byte("This body is super")
+	large := bytes.Repeat([]byte("This body is super large. "), 100)
+
+	tt := []struct {
+		name    string
+		body    []byte
+		sig     string
+		options []Option
+		status  int
+		spilled bool
+	}{
+		{"small", small, SignSHA256(small, "supersecret"), nil, http.StatusOK, false},
+		{"large", large, SignSHA256(large, "supersecret"), nil, http.StatusOK, true},
+		{"large rotated", large, SignSHA256(large, "new"), []Option{OptionSecrets("old", "new")}, http.StatusOK, true},
+		{"large mismatch", large, SignSHA256(large, "wrongsecret"), nil, http.StatusForbidden, false},
+		{"large malformed", large, "sha256=zz", nil, http.StatusBadRequest, false},
+		{"large missing", large, "", nil, http.StatusForbidden, false},
+		{"large too large", large, SignSHA256(large, "supersecret"), []Option{OptionMaxBodyBytes(1000)}, http.StatusRequestEntityTooLarge, false},
+		{"large custom validator", large, SignSHA256(large, "supersecret"), []Option{OptionSignatureValidator(SHA256Validator)}, http.StatusOK, false},
+		{"large prefix", large, Sign(large, "supersecret", sha256.New, "sha256:"), []Option{OptionSignaturePrefix("sha256:")}, http.StatusOK, true},
+	}
+
+	for _, tc := range tt {
+		dir := t.TempDir()
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader(tc.body))
+		req.ContentLength = -1
+		req.Header.Set(GithubSignatureHeader256, tc.sig)
+		rec := httptest.NewRecorder()
+
+		spilled := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, spilled = r.Body.(*spillBody)
+
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b, tc.body) {
+				t.Errorf("%s: expected body of %d bytes; got %d bytes", tc.name, len(tc.body), len(b))
+			}
+
+			w.Write([]byte("ok"))
+		})
+
+		xhs := Handler256(x, "supersecret", append([]Option{OptionSpillToDisk(512, dir)}, tc.options...)...)
+		xhs.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+
+		if spilled != tc.spilled {
+			t.Errorf("%s: expected spilled %v; got %v", tc.name, tc.spilled, spilled)
+		}
+
+		files, _ := os.ReadDir(dir)
+		if len(files) != 0 {
+			t.Errorf("%s: expected temporary files to be removed; got %d", tc.name, len(files))
+		}
+	}
+}
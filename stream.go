@@ -0,0 +1,225 @@
+package hmacsig
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// streamVerifier computes the HMAC of a body written to it for each accepted
+// secret, allowing a signature to be verified without buffering the body in
+// memory
+type streamVerifier struct {
+	header string
+	prefix string
+	sig    []byte
+	macs   []hash.Hash
+}
+
+// canStream reports whether the configuration permits verifying a body as it
+// streams, which requires a single header and a known hash and signature
+// format rather than an opaque validator, and a signature available before
+// the body is read
+func (xh *hmacSig) canStream() bool {
+	return len(xh.checks) == 1 && !xh.requireAll && xh.hash != nil && xh.format != nil &&
+		xh.trailer == "" && !xh.commaSeparated && !xh.multipleHeaders &&
+		xh.bodyNormalizer == nil
+}
+
+// newStreamVerifier checks the presence and format of the signature of r and
+// returns a streamVerifier for it
+func (xh *hmacSig) newStreamVerifier(r *http.Request) (*streamVerifier, error) {
+	c := xh.checks[0]
+
+	sig := xh.signature(r, c)
+	if sig == "" {
+		return nil, ErrMissingSignature
+	}
+
+	if !c.format.wellFormed(sig) {
+		return nil, xh.malformed(c, sig)
+	}
+
+	secrets, err := xh.resolveSecrets(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sv := &streamVerifier{
+		header: c.header,
+		prefix: c.format.prefix,
+		macs:   make([]hash.Hash, len(secrets)),
+	}
+	sv.sig, _ = c.format.decodeTo(nil, sig)
+	for i, secret := range secrets {
+		sv.macs[i] = hmac.New(xh.hash, []byte(secret))
+	}
+
+	return sv, nil
+}
+
+// Write implements io.Writer, adding p to the HMAC for each secret
+func (sv *streamVerifier) Write(p []byte) (int, error) {
+	for _, mac := range sv.macs {
+		mac.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// verify compares the signature against the HMAC for each secret of the
+// body written so far. Every secret is checked regardless so the time taken
+// does not reveal which of them matched.
+func (sv *streamVerifier) verify() (Verification, error) {
+	idx := -1
+	for i, mac := range sv.macs {
+		if equalDigest(mac.Sum(nil), sv.sig) && idx < 0 {
+			idx = i
+		}
+	}
+
+	if idx < 0 {
+		return Verification{}, ErrSignatureMismatch
+	}
+
+	return Verification{Header: sv.header, Prefix: sv.prefix, SecretIndex: idx}, nil
+}
+
+// OptionVerifyOnRead configures a streaming mode in which the body is not
+// read before the wrapped handler is called. Instead the HMAC is computed as
+// the wrapped handler reads r.Body, and the signature checked when it reaches
+// the end: a valid body ends with io.EOF as usual, while an invalid one ends
+// with an error wrapping ErrSignatureMismatch in its place, or ErrBodyTooLarge
+// once the limit configured by OptionMaxBodyBytes is exceeded. This avoids
+// buffering large bodies and the latency of reading them in full up front.
+//
+// As the wrapped handler runs before the body is verified, it must treat the
+// body as untrusted until r.Body has returned io.EOF, and act on it only in
+// ways that are safe to act on for a body later found invalid; this mode is
+// intended for idempotent handlers only. Signatures which are missing or
+// malformed, and secrets which cannot be resolved, are still rejected before
+// the wrapped handler is called.
+//
+// Once the wrapped handler returns, any unread remainder of the body is read
+// and verified, and the outcome logged and reported to observers. As the
+// response has by then been written, the failure handlers are not called.
+// FromContext reports no Verification, and OptionOnVerified is not called.
+//
+// Like OptionSpillToDisk it applies only when the hash and signature format
+// are known, and not with OptionBodyContext, OptionBodyTap, OptionReportOnly,
+// OptionNonceStore or OptionDeduper, for which bodies are verified before the
+// wrapped handler as usual.
+func OptionVerifyOnRead(mux *hmacSig) {
+	mux.verifyOnRead = true
+}
+
+// canVerifyOnRead reports whether requests are to be verified as the wrapped
+// handler reads their body
+func (xh *hmacSig) canVerifyOnRead() bool {
+	return xh.verifyOnRead && xh.canStream() && !xh.bodyContext && !xh.reportOnly &&
+		xh.bodyTap == nil && xh.nonceStore == nil && xh.deduper == nil
+}
+
+// serveVerifyOnRead calls the wrapped handler with the body of r verified as
+// it is read, then verifies any remainder the wrapped handler did not read
+func (xh *hmacSig) serveVerifyOnRead(w http.ResponseWriter, r *http.Request) {
+	sv, err := xh.newStreamVerifier(r)
+	if err != nil {
+		xh.fail(w, r, err)
+		return
+	}
+
+	if err := xh.checkReplay(r); err != nil {
+		xh.fail(w, r, err)
+		return
+	}
+
+	body := r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+
+	vr := &verifyingReader{r: body, sv: sv, max: xh.maxBodyBytes}
+	r.Body = vr
+	r.GetBody = nil
+
+	xh.serve(w, withDisposition(r, DispositionPending))
+
+	if err := vr.finish(); err != nil {
+		if xh.logger != nil {
+			xh.logger.Printf("%v: remote_addr=%q header=%q (after handler)", err, r.RemoteAddr, xh.checkedHeaders())
+		}
+
+		xh.observe(w, resultFor(err), r)
+		return
+	}
+
+	xh.observe(w, ResultOK, r)
+}
+
+// verifyingReader passes the body read through it to a streamVerifier,
+// verifying the signature on reaching the end of the body
+type verifyingReader struct {
+	r   io.Reader
+	sv  *streamVerifier
+	max int64
+
+	n    int64
+	done bool
+	err  error
+}
+
+// Read implements io.Reader, returning the verification error in place of
+// io.EOF if the signature is invalid
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	if vr.done {
+		if vr.err != nil {
+			return 0, vr.err
+		}
+		return 0, io.EOF
+	}
+
+	n, err := vr.r.Read(p)
+	vr.n += int64(n)
+	if vr.max > 0 && vr.n > vr.max {
+		n -= int(vr.n - vr.max)
+		vr.done, vr.err = true, ErrBodyTooLarge
+		vr.sv.Write(p[:n])
+		return n, vr.err
+	}
+
+	vr.sv.Write(p[:n])
+
+	if err == io.EOF {
+		vr.done = true
+		if _, vr.err = vr.sv.verify(); vr.err != nil {
+			return n, vr.err
+		}
+	} else if err != nil {
+		vr.done, vr.err = true, fmt.Errorf("%w: %v", ErrReadBody, err)
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer, closing the underlying body if it is an
+// io.Closer
+func (vr *verifyingReader) Close() error {
+	if c, ok := vr.r.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// finish reads and verifies any remainder of the body not yet read, returning
+// the outcome of verification
+func (vr *verifyingReader) finish() error {
+	if !vr.done {
+		io.Copy(io.Discard, vr)
+	}
+
+	return vr.err
+}
@@ -0,0 +1,84 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyOnRead(t *testing.T) {
+	body := []byte("This is the body of the request")
+
+	tt := []struct {
+		name     string
+		sig      string
+		options  []Option
+		read     bool
+		called   bool
+		readErr  error
+		result   Result
+		status   int
+		buffered bool
+	}{
+		{"valid", SignSHA1(body, "supersecret"), nil, true, true, nil, ResultOK, http.StatusOK, false},
+		{"valid, unread", SignSHA1(body, "supersecret"), nil, false, true, nil, ResultOK, http.StatusOK, false},
+		{"mismatch", SignSHA1(body, "wrongsecret"), nil, true, true, ErrSignatureMismatch, ResultFailed, http.StatusOK, false},
+		{"mismatch, unread", SignSHA1(body, "wrongsecret"), nil, false, true, nil, ResultFailed, http.StatusOK, false},
+		{"too large", SignSHA1(body, "supersecret"), []Option{OptionMaxBodyBytes(8)}, true, true, ErrBodyTooLarge, ResultError, http.StatusOK, false},
+		{"missing", "", nil, true, false, nil, ResultMissing, http.StatusForbidden, false},
+		{"malformed", "sha1=zz", nil, true, false, nil, ResultFailed, http.StatusBadRequest, false},
+		{"body context", SignSHA1(body, "wrongsecret"), []Option{OptionBodyContext}, true, false, nil, ResultFailed, http.StatusForbidden, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", io.NopCloser(bytes.NewReader(body)))
+		req.ContentLength = -1
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		called := false
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+
+			if _, ok := FromContext(r.Context()); ok != tc.buffered {
+				t.Errorf("%s: expected verification in context %v; got %v", tc.name, tc.buffered, ok)
+			}
+
+			if !tc.read {
+				return
+			}
+
+			b, err := io.ReadAll(r.Body)
+			if !errors.Is(err, tc.readErr) || (tc.readErr == nil && err != nil) {
+				t.Errorf("%s: expected read error '%v'; got '%v'", tc.name, tc.readErr, err)
+			}
+
+			if tc.readErr == nil && !bytes.Equal(b, body) {
+				t.Errorf("%s: expected body '%s'; got '%s'", tc.name, body, b)
+			}
+		})
+
+		var results []Result
+		observer := func(result Result, r *http.Request) {
+			results = append(results, result)
+		}
+
+		options := append([]Option{OptionVerifyOnRead, OptionObserver(observer)}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		if called != tc.called {
+			t.Errorf("%s: expected handler called %v; got %v", tc.name, tc.called, called)
+		}
+
+		if len(results) != 1 || results[0] != tc.result {
+			t.Errorf("%s: expected result %v; got %v", tc.name, tc.result, results)
+		}
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+	}
+}
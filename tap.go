@@ -0,0 +1,125 @@
+package hmacsig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MsgBodyTap is the message returned in the body when the body could not be
+// copied to the tap configured by OptionBodyTap and OptionBodyTapRequired is
+// set
+const MsgBodyTap = "Unable to archive request body"
+
+// ErrBodyTap is the failure reason when the body could not be copied to the
+// tap configured by OptionBodyTap and OptionBodyTapRequired is set. The
+// underlying error is wrapped.
+var ErrBodyTap = errors.New("hmacsig: unable to copy body to tap")
+
+// OptionBodyTap configures a function returning a writer to which the body of
+// each verified request is copied, for instance to archive it, before the
+// wrapped handler is called. Requests failing verification are not copied. If
+// the writer is an io.Closer it is closed once the body is copied.
+//
+// Errors returned by fn or the writer are logged to the Logger configured by
+// OptionLogger, and the request is delivered regardless with the error in the
+// TapErr of its Verification, which observers and the wrapped handler read
+// with FromContext, unless OptionBodyTapRequired is set. By default bodies are
+// not copied.
+func OptionBodyTap(fn func(r *http.Request) (io.Writer, error)) Option {
+	return func(mux *hmacSig) {
+		mux.bodyTap = fn
+	}
+}
+
+// OptionBodyTapRequired configures requests whose body could not be copied to
+// the tap configured by OptionBodyTap to be passed to the body tap error
+// handler rather than delivered
+func OptionBodyTapRequired(mux *hmacSig) {
+	mux.bodyTapRequired = true
+}
+
+// OptionBodyTapErrorHandler configures the http.Handler called when the body
+// could not be copied to the tap configured by OptionBodyTap and
+// OptionBodyTapRequired is set
+func OptionBodyTapErrorHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.bodyTapErrorHandler = handler
+	}
+}
+
+// DefaultBodyTapErrorHandler is the default response to the body not being
+// copied to the tap. The underlying error is not exposed to the client.
+func DefaultBodyTapErrorHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgBodyTap, http.StatusInternalServerError)
+}
+
+// JSONBodyTapErrorHandler responds to the body not being copied to the tap
+// with a JSON body of the form {"error":"..."}
+func JSONBodyTapErrorHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgBodyTap, http.StatusInternalServerError)
+}
+
+// tap copies the verified body of r to the configured tap, reading it from
+// getBody or, lacking one, from body rewound after copying, and returns any
+// error, which is logged unless the tap is required
+func (xh *hmacSig) tap(r *http.Request, body io.Reader, getBody func() (io.ReadCloser, error)) error {
+	if xh.bodyTap == nil {
+		return nil
+	}
+
+	err := xh.copyToTap(r, body, getBody)
+	if err != nil && !xh.bodyTapRequired && xh.logger != nil {
+		xh.logger.Printf("%v: remote_addr=%q", err, r.RemoteAddr)
+	}
+
+	return err
+}
+
+// copyToTap copies the verified body of r to a writer from the configured tap
+func (xh *hmacSig) copyToTap(r *http.Request, body io.Reader, getBody func() (io.ReadCloser, error)) error {
+	w, err := xh.bodyTap(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBodyTap, err)
+	}
+
+	err = copyBody(w, body, getBody)
+	if c, ok := w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBodyTap, err)
+	}
+
+	return nil
+}
+
+// copyBody copies the body supplied by getBody to w or, lacking a getBody,
+// body, which is rewound to its start afterwards
+func copyBody(w io.Writer, body io.Reader, getBody func() (io.ReadCloser, error)) error {
+	if getBody != nil {
+		rc, err := getBody()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		_, err = io.Copy(w, rc)
+		return err
+	}
+
+	s, ok := body.(io.ReadSeeker)
+	if !ok {
+		return errors.New("body cannot be reread")
+	}
+
+	_, err := io.Copy(w, s)
+	if _, serr := s.Seek(0, io.SeekStart); err == nil {
+		err = serr
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestBodyTap(t *testing.T) {
+	body := "This is the body of the request"
+	valid := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+
+	tt := []struct {
+		name    string
+		sig     string
+		fail    bool
+		options []Option
+		status  int
+		tapped  bool
+		logged  bool
+	}{
+		{"tapped", valid, false, nil, http.StatusOK, true, false},
+		{"failed verification", "sha1=587eed5390987ba9ee890cafa946eed9dacf2e52", false, nil, http.StatusForbidden, false, false},
+		{"spilled", valid, false, []Option{OptionSpillToDisk(4, t.TempDir())}, http.StatusOK, true, false},
+		{"reuse GetBody", valid, false, []Option{OptionReuseGetBody}, http.StatusOK, true, false},
+		{"tap error", valid, true, nil, http.StatusOK, false, true},
+		{"tap error, required", valid, true, []Option{OptionBodyTapRequired}, http.StatusInternalServerError, false, true},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(body)))
+		req.Header.Set(GithubSignatureHeader, tc.sig)
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != body {
+				t.Errorf("%s: expected handler to read '%s'; got '%s'", tc.name, body, b)
+			}
+
+			w.Write([]byte("ok"))
+		})
+
+		var tapped bytes.Buffer
+		tap := OptionBodyTap(func(r *http.Request) (io.Writer, error) {
+			if tc.fail {
+				return failWriter{}, nil
+			}
+
+			return &tapped, nil
+		})
+
+		var tapErr error
+		observer := OptionObserver(func(res Result, r *http.Request) {
+			v, _ := FromContext(r.Context())
+			tapErr = v.TapErr
+		})
+
+		var logs strings.Builder
+		options := append([]Option{tap, observer, OptionLogger(log.New(&logs, "", 0))}, tc.options...)
+		Handler(x, "supersecret", options...).ServeHTTP(rec, req)
+
+		want := tc.logged && tc.status == http.StatusOK
+		if observed := errors.Is(tapErr, ErrBodyTap); observed != want {
+			t.Errorf("%s: expected tap error observed %v; got '%v'", tc.name, want, tapErr)
+		}
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if tc.tapped && tapped.String() != body {
+			t.Errorf("%s: expected tap to receive '%s'; got '%s'", tc.name, body, tapped.String())
+		}
+		if !tc.tapped && tapped.Len() != 0 {
+			t.Errorf("%s: expected nothing tapped; got '%s'", tc.name, tapped.String())
+		}
+
+		if logged := strings.Contains(logs.String(), ErrBodyTap.Error()); logged != tc.logged {
+			t.Errorf("%s: expected logged %v; got '%s'", tc.name, tc.logged, logs.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package hmacsig
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MsgReadTimeout is the message returned in the body when the request body
+// was not read within the duration configured by OptionReadTimeout
+const MsgReadTimeout = "Timed out reading request body"
+
+// ErrReadTimeout is the failure reason when the request body was not read
+// within the duration configured by OptionReadTimeout
+var ErrReadTimeout = errors.New("hmacsig: timed out reading request body")
+
+// errCanceled is returned by timedRead when the request context is done
+// before the body is read, in which case the client is gone and no response
+// is written
+var errCanceled = errors.New("hmacsig: request canceled")
+
+// OptionReadTimeout configures the maximum time spent reading the request
+// body, protecting against slow clients tying up the handler before their
+// signature can be checked. Requests whose body is not read in time are passed
+// to the read timeout handler, which must not read the body; the abandoned
+// read ends once the connection is closed or reused.
+//
+// When spilling to disk, the head and the remainder of the body are each
+// allowed d. A value of zero or less, the default, means no limit. Consider
+// http.Server.ReadTimeout too, which bounds the whole request at the
+// connection level.
+func OptionReadTimeout(d time.Duration) Option {
+	return func(mux *hmacSig) {
+		mux.readTimeout = d
+	}
+}
+
+// OptionReadTimeoutHandler configures the http.Handler called when the
+// request body is not read within the duration configured by
+// OptionReadTimeout
+func OptionReadTimeoutHandler(handler http.Handler) Option {
+	return func(mux *hmacSig) {
+		mux.readTimeoutHandler = handler
+	}
+}
+
+// DefaultReadTimeoutHandler is the default response to the request body not
+// being read within the duration configured by OptionReadTimeout
+func DefaultReadTimeoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, MsgReadTimeout, http.StatusRequestTimeout)
+}
+
+// JSONReadTimeoutHandler responds to the request body not being read in time
+// with a JSON body of the form {"error":"..."}
+func JSONReadTimeoutHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, MsgReadTimeout, http.StatusRequestTimeout)
+}
+
+// timedRead calls read with the body of r, returning ErrReadTimeout without
+// waiting for it if it does not return within the configured read timeout, or
+// errCanceled if the context of r is done first. Unless the context can never
+// be done and no timeout is configured, read is run on its own goroutine and
+// must not share state with the caller that is used after either.
+func (xh *hmacSig) timedRead(r *http.Request, read func(body io.Reader) error) error {
+	ctx := r.Context()
+	if xh.readTimeout <= 0 && ctx.Done() == nil {
+		return read(r.Body)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- read(r.Body)
+	}()
+
+	var timeout <-chan time.Time
+	if xh.readTimeout > 0 {
+		t := time.NewTimer(xh.readTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() != nil {
+			return errCanceled
+		}
+		return err
+	case <-timeout:
+		return ErrReadTimeout
+	case <-ctx.Done():
+		return errCanceled
+	}
+}
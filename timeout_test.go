@@ -0,0 +1,104 @@
+package hmacsig
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReadTimeout(t *testing.T) {
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tt := []struct {
+		name    string
+		delay   time.Duration
+		options []Option
+		status  int
+	}{
+		{"no timeout", time.Millisecond, nil, http.StatusOK},
+		{"in time", 0, []Option{OptionReadTimeout(time.Minute)}, http.StatusOK},
+		{"timed out", 5 * time.Millisecond, []Option{OptionReadTimeout(10 * time.Millisecond)}, http.StatusRequestTimeout},
+		{"timed out, handler", 5 * time.Millisecond, []Option{OptionReadTimeout(10 * time.Millisecond), OptionReadTimeoutHandler(teapot)}, http.StatusTeapot},
+		{"timed out, spilled", 5 * time.Millisecond, []Option{OptionReadTimeout(10 * time.Millisecond), OptionSpillToDisk(4, t.TempDir())}, http.StatusRequestTimeout},
+		{"blocked", time.Minute, []Option{OptionReadTimeout(50 * time.Millisecond)}, http.StatusRequestTimeout},
+		{"blocked, spilled", time.Minute, []Option{OptionReadTimeout(50 * time.Millisecond), OptionSpillToDisk(4, t.TempDir())}, http.StatusRequestTimeout},
+	}
+
+	for _, tc := range tt {
+		body := slowReader{iotest.OneByteReader(bytes.NewReader([]byte("This is the body of the request"))), tc.delay}
+		req, _ := http.NewRequest("POST", "localhost", body)
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+		rec := httptest.NewRecorder()
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		start := time.Now()
+		Handler(x, "supersecret", tc.options...).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d; got %d", tc.name, tc.status, rec.Code)
+		}
+
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%s: expected the handler not to wait for the body; took %v", tc.name, d)
+		}
+	}
+}
+
+// unwrittenResponse is an http.ResponseWriter failing the test if a response
+// is written
+type unwrittenResponse struct {
+	t *testing.T
+}
+
+func (uw unwrittenResponse) Header() http.Header {
+	return http.Header{}
+}
+
+func (uw unwrittenResponse) Write(p []byte) (int, error) {
+	uw.t.Errorf("expected no response; got '%s'", p)
+	return len(p), nil
+}
+
+func (uw unwrittenResponse) WriteHeader(code int) {
+	uw.t.Errorf("expected no response; got status %d", code)
+}
+
+func TestReadCanceled(t *testing.T) {
+	tt := []struct {
+		name    string
+		options []Option
+	}{
+		{"buffered", nil},
+		{"read timeout", []Option{OptionReadTimeout(time.Minute)}},
+		{"spilled", []Option{OptionSpillToDisk(4, t.TempDir())}},
+	}
+
+	for _, tc := range tt {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		body := slowReader{bytes.NewReader([]byte("This is the body of the request")), time.Minute}
+		req, _ := http.NewRequestWithContext(ctx, "POST", "localhost", body)
+		req.Header.Set(GithubSignatureHeader, "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c")
+
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("%s: should not be executed", tc.name)
+		})
+
+		start := time.Now()
+		Handler(x, "supersecret", tc.options...).ServeHTTP(unwrittenResponse{t}, req)
+
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%s: expected the handler not to wait for the body; took %v", tc.name, d)
+		}
+	}
+}
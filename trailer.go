@@ -0,0 +1,16 @@
+package hmacsig
+
+// OptionTrailer configures the signature to be read from the named HTTP
+// trailer, for clients which compute the signature as they stream the body.
+// Trailers are only available once the body is fully read, which the
+// middleware does before verifying. A missing trailer is treated as a missing
+// signature.
+//
+// Clients must declare the trailer in the Trailer header for it to be
+// received. When set, this takes precedence over OptionQueryParam, and
+// OptionSpillToDisk has no effect.
+func OptionTrailer(name string) Option {
+	return func(mux *hmacSig) {
+		mux.trailer = name
+	}
+}
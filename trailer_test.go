@@ -0,0 +1,54 @@
+package hmacsig
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrailer(t *testing.T) {
+	body := "This is the body of the request"
+
+	tt := []struct {
+		name    string
+		trailer string
+		header  string
+		status  int
+	}{
+		{"valid trailer", SignSHA1([]byte(body), "supersecret"), "", http.StatusOK},
+		{"invalid trailer", SignSHA1([]byte(body), "wrongsecret"), "", http.StatusForbidden},
+		{"missing trailer", "", "", http.StatusForbidden},
+		{"header ignored", "", SignSHA1([]byte(body), "supersecret"), http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != body {
+				t.Errorf("%s: expected body '%s'; got '%s'", tc.name, body, b)
+			}
+		})
+
+		srv := httptest.NewServer(Handler(x, "supersecret", OptionTrailer(GithubSignatureHeader)))
+
+		// a reader of unknown length sends the body chunked, with trailers
+		req, _ := http.NewRequest("POST", srv.URL, io.MultiReader(strings.NewReader(body)))
+		req.Header.Set(GithubSignatureHeader, tc.header)
+		if tc.trailer != "" {
+			req.Trailer = http.Header{GithubSignatureHeader: {tc.trailer}}
+		}
+
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		srv.Close()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d; got %v", tc.name, tc.status, res.Status)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package hmacsig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SigningTransport is an http.RoundTripper which signs the body of outgoing
+// requests such that they validate against Handler on the receiving end.
+type SigningTransport struct {
+	// Transport is the underlying http.RoundTripper used to send the signed
+	// request. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Secret is the shared secret the body is signed with
+	Secret string
+
+	// Header is the HTTP Header the signature is set on. If empty,
+	// GithubSignatureHeader256 is used.
+	Header string
+
+	// Sign computes the signature header value. If nil, SignSHA256 is used.
+	Sign func(body []byte, secret string) string
+}
+
+// RoundTrip implements http.RoundTripper. The request body is read in full,
+// signed, and replaced by a buffered copy so it can be sent and, via GetBody,
+// resent on retry. The passed request is not modified.
+func (st *SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var b []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		b, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sreq := req.Clone(req.Context())
+	if b != nil {
+		sreq.Body = io.NopCloser(bytes.NewReader(b))
+		sreq.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+		sreq.ContentLength = int64(len(b))
+	}
+
+	sign := st.Sign
+	if sign == nil {
+		sign = SignSHA256
+	}
+
+	header := st.Header
+	if header == "" {
+		header = GithubSignatureHeader256
+	}
+
+	sreq.Header.Set(header, sign(b, st.Secret))
+
+	transport := st.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return transport.RoundTrip(sreq)
+}
+
+// VerifyingTransport is an http.RoundTripper which verifies the signature of
+// the body of incoming responses, for upstreams which sign their responses.
+// It fails closed: responses without a valid signature are discarded and an
+// error returned in their place.
+type VerifyingTransport struct {
+	// Transport is the underlying http.RoundTripper used to send the request.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Secret is the shared secret the body is signed with
+	Secret string
+
+	// Header is the HTTP Header the signature is read from. If empty,
+	// GithubSignatureHeader256 is used.
+	Header string
+
+	// Validator validates the signature. If nil, SHA256Validator is used.
+	Validator SignatureValidator
+}
+
+// RoundTrip implements http.RoundTripper. The response body is read in full
+// and verified, and on success replaced by a buffered copy. If the signature
+// is missing or invalid, the response body is closed and ErrMissingSignature
+// or ErrSignatureMismatch returned; if it cannot be read, an error wrapping
+// ErrReadBody.
+func (vt *VerifyingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := vt.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadBody, err)
+	}
+
+	header := vt.Header
+	if header == "" {
+		header = GithubSignatureHeader256
+	}
+
+	validator := vt.Validator
+	if validator == nil {
+		validator = SHA256Validator
+	}
+
+	sig := res.Header.Get(header)
+	if sig == "" {
+		return nil, ErrMissingSignature
+	}
+
+	if !Validate(b, sig, vt.Secret, validator) {
+		return nil, ErrSignatureMismatch
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	res.ContentLength = int64(len(b))
+
+	return res, nil
+}
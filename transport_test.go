@@ -0,0 +1,136 @@
+package hmacsig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSigningTransport(t *testing.T) {
+	tt := []struct {
+		method    string
+		body      io.Reader
+		transport *SigningTransport
+		server    func(http.Handler) http.Handler
+		status    int
+	}{
+		{"POST", strings.NewReader("This body is super"), &SigningTransport{Secret: "supersecret"},
+			func(h http.Handler) http.Handler { return Handler256(h, "supersecret") }, http.StatusOK},
+		{"POST", bytes.NewReader([]byte("This body is super")), &SigningTransport{Secret: "supersecret", Header: GithubSignatureHeader, Sign: SignSHA1},
+			func(h http.Handler) http.Handler { return Handler(h, "supersecret") }, http.StatusOK},
+		{"GET", nil, &SigningTransport{Secret: "supersecret"},
+			func(h http.Handler) http.Handler { return Handler256(h, "supersecret") }, http.StatusOK},
+		{"POST", strings.NewReader("This body is super"), &SigningTransport{Secret: "wrongsecret"},
+			func(h http.Handler) http.Handler { return Handler256(h, "supersecret") }, http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		x := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+
+		srv := httptest.NewServer(tc.server(x))
+
+		client := &http.Client{Transport: tc.transport}
+		req, _ := http.NewRequest(tc.method, srv.URL, tc.body)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("expected status %d; got %v", tc.status, res.Status)
+		}
+
+		if req.Header.Get(GithubSignatureHeader256) != "" {
+			t.Error("expected original request not to be modified")
+		}
+
+		srv.Close()
+	}
+}
+
+func TestSigningTransportGetBody(t *testing.T) {
+	var sent *http.Request
+	inner := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	st := &SigningTransport{Transport: inner, Secret: "supersecret"}
+	req, _ := http.NewRequest("POST", "http://localhost", strings.NewReader("This body is super"))
+
+	_, err := st.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		rc, err := sent.GetBody()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(rc)
+		if string(b) != "This body is super" {
+			t.Errorf("expected GetBody to return 'This body is super'; got '%s'", b)
+		}
+
+		if !SHA256Validator(b, sent.Header.Get(GithubSignatureHeader256), "supersecret") {
+			t.Error("expected GetBody body to validate against the signature")
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestVerifyingTransport(t *testing.T) {
+	body := []byte("This body is super")
+
+	tt := []struct {
+		name      string
+		header    string
+		sig       string
+		transport *VerifyingTransport
+		err       error
+	}{
+		{"valid", GithubSignatureHeader256, SignSHA256(body, "supersecret"), &VerifyingTransport{Secret: "supersecret"}, nil},
+		{"valid sha1", GithubSignatureHeader, SignSHA1(body, "supersecret"), &VerifyingTransport{Secret: "supersecret", Header: GithubSignatureHeader, Validator: SHA1Validator}, nil},
+		{"invalid", GithubSignatureHeader256, SignSHA256(body, "wrongsecret"), &VerifyingTransport{Secret: "supersecret"}, ErrSignatureMismatch},
+		{"missing", "", "", &VerifyingTransport{Secret: "supersecret"}, ErrMissingSignature},
+	}
+
+	for _, tc := range tt {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.header != "" {
+				w.Header().Set(tc.header, tc.sig)
+			}
+			w.Write(body)
+		}))
+
+		client := &http.Client{Transport: tc.transport}
+		res, err := client.Get(srv.URL)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected error %v; got %v", tc.name, tc.err, err)
+		}
+
+		if err == nil {
+			b, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if !bytes.Equal(b, body) {
+				t.Errorf("%s: expected body '%s'; got '%s'", tc.name, body, b)
+			}
+		}
+
+		srv.Close()
+	}
+}
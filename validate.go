@@ -0,0 +1,54 @@
+package hmacsig
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// Validate reports whether sig is a valid signature of body for the given
+// secret using the SignatureValidator v.
+//
+// Validate is intended for use outside of HTTP middleware, for instance by a
+// message-queue consumer receiving the body and signature separately.
+func Validate(body []byte, sig, secret string, v SignatureValidator) bool {
+	if sig == "" {
+		return false
+	}
+
+	return v(body, sig, secret)
+}
+
+// ValidateRequest reports whether the signature found in the given header of r
+// is a valid signature of body for the given secret using the
+// SignatureValidator v.
+//
+// ValidateRequest does not consume or rewind r.Body; the caller is responsible
+// for reading the body and passing it in.
+func ValidateRequest(r *http.Request, body []byte, header, secret string, v SignatureValidator) bool {
+	return Validate(body, r.Header.Get(header), secret, v)
+}
+
+// ValidateReader reports whether sig is the case-insensitive prefix, e.g.
+// "sha256=", followed by the hex encoded HMAC computed with h of everything
+// read from r, for the given secret. r is streamed through the HMAC rather
+// than buffered, suiting large files or streamed message-queue payloads.
+//
+// An invalid signature reports false with a nil error. If r cannot be read the
+// returned error wraps ErrReadBody. A malformed signature is reported without
+// reading r.
+func ValidateReader(r io.Reader, sig, secret string, h func() hash.Hash, prefix string) (bool, error) {
+	sigDigest, err := decodeSignature(sig, prefix, EncodingHex)
+	if err != nil {
+		return false, nil
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	if _, err := io.Copy(mac, r); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrReadBody, err)
+	}
+
+	return equalDigest(mac.Sum(nil), sigDigest), nil
+}
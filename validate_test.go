@@ -0,0 +1,74 @@
+package hmacsig
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		sig       string
+		secret    string
+		body      string
+		validator SignatureValidator
+		valid     bool
+	}{
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "supersecret", "This is the body of the request", SHA1Validator, true},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", "This body is super", SHA256Validator, true},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "supersecret", "This is the body of the request", SHA256Validator, false},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "wrongsecret", "This is the body of the request", SHA1Validator, false},
+		{"", "supersecret", "This is the body of the request", SHA1Validator, false},
+	}
+
+	for _, tc := range tt {
+		if v := Validate([]byte(tc.body), tc.sig, tc.secret, tc.validator); v != tc.valid {
+			t.Errorf("expected Validate %v for '%v'; got %v", tc.valid, tc.sig, v)
+		}
+
+		req, _ := http.NewRequest("POST", "localhost", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set("X-Signature", tc.sig)
+
+		if v := ValidateRequest(req, []byte(tc.body), "X-Signature", tc.secret, tc.validator); v != tc.valid {
+			t.Errorf("expected ValidateRequest %v for '%v'; got %v", tc.valid, tc.sig, v)
+		}
+	}
+}
+
+func TestValidateReader(t *testing.T) {
+	tt := []struct {
+		sig    string
+		secret string
+		body   string
+		valid  bool
+	}{
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", "This body is super", true},
+		{"SHA256=814E50A60CF9B4EED0E28EFAD0C801DB5D93D4CC0F41C5BF2C6E0183CE0B9B23", "EvenDifferentKey", "This body is super", true},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "wrongsecret", "This body is super", false},
+		{"sha256=814e50a60cf9b4eed0e28efad0c801db5d93d4cc0f41c5bf2c6e0183ce0b9b23", "EvenDifferentKey", "This body is different", false},
+		{"sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c", "supersecret", "This is the body of the request", false},
+		{"sha256=zz", "EvenDifferentKey", "This body is super", false},
+		{"", "EvenDifferentKey", "This body is super", false},
+	}
+
+	for _, tc := range tt {
+		valid, err := ValidateReader(iotest.OneByteReader(strings.NewReader(tc.body)), tc.sig, tc.secret, sha256.New, "sha256=")
+		if err != nil {
+			t.Errorf("unexpected error for '%v': %v", tc.sig, err)
+		}
+
+		if valid != tc.valid {
+			t.Errorf("expected ValidateReader %v for '%v'; got %v", tc.valid, tc.sig, valid)
+		}
+	}
+
+	sig := "sha1=0de7dbe42dfef6ed31d9d0d4374c962209e5339c"
+	if _, err := ValidateReader(iotest.ErrReader(errors.New("boom")), sig, "supersecret", sha1.New, "sha1="); !errors.Is(err, ErrReadBody) {
+		t.Errorf("expected ErrReadBody; got %v", err)
+	}
+}